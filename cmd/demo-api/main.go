@@ -3,12 +3,14 @@ package main
 
 import (
 	"fmt"
-	"time"
+	"os"
+	"syscall"
 
 	"go-demo/config"
 	"go-demo/config/di"
 	"go-demo/internal/middleware"
 	"go-demo/internal/router"
+	"go-demo/internal/service"
 	"go-demo/pkg/ginx"
 
 	"github.com/fvbock/endless"
@@ -17,19 +19,47 @@ import (
 )
 
 func main() {
+	// 配置校验, 配置有误程序不允许启动
+	config.MustBind(&config.App)
+
+	// 链路追踪
+	di.TracerProvider()
+
+	// 诊断服务
+	di.ServeDiag(config.GetInt("api_diag_port"), service.Auth.AdminRequest)
+
 	// 实例化 Gin
 	if lo.Contains([]string{"prod", "stage"}, config.RuntimeEnv()) {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.Default()
 
+	// 配置热更新
+	di.WatchConfig()
+	qpsLimiter := middleware.NewQPSLimiter(config.GetInt("qps_limit"))
+	config.OnChange("qps_limit", func() {
+		qpsLimiter.SetQPS(config.GetInt("qps_limit"))
+	})
+	timeoutControl := middleware.NewTimeoutControl(config.GetDuration("timeout"))
+	config.OnChange("timeout", func() {
+		timeoutControl.SetTimeout(config.GetDuration("timeout"))
+	})
+
+	// 健康检查, 在限流与超时控制之前注册, 高负载时探针不被限流
+	router.Health(r)
+
 	r.Use(
-		middleware.Recovery(),                           // panic 处理
-		middleware.CORS(),                               // 跨域处理
-		middleware.QPSLimit(config.GetInt("qps_limit")), // 限流
-		middleware.Timeout(time.Duration(config.GetInt("timeout"))*time.Second), // 超时控制
+		middleware.Trace(),       // 链路追踪
+		middleware.RequestID(),   // 请求 ID
+		middleware.Recovery(),    // panic 处理
+		middleware.CORS(),        // 跨域处理
+		qpsLimiter.Handler(),     // 限流
+		timeoutControl.Handler(), // 超时控制
 	)
 
+	// 管理接口
+	router.Admin(r)
+
 	// 加载路由 DEMO
 	router.Account(r)
 
@@ -39,9 +69,19 @@ func main() {
 	})
 
 	// Run Gin
-	addr := fmt.Sprintf(":%d", config.GetInt("server_port"))
-	if err := endless.ListenAndServe(addr, r); err != nil {
+	// endless 收到 SIGINT/SIGTERM/SIGHUP 后停止接收新请求, 并等待处理中的请求完成, 超时强制中断
+	endless.DefaultHammerTime = di.ShutdownTimeout()
+	srv := endless.NewServer(fmt.Sprintf(":%d", config.GetInt("server_port")), r)
+	// 停止接收前先让就绪检查失败
+	for _, sig := range []os.Signal{syscall.SIGINT, syscall.SIGTERM} {
+		if err := srv.RegisterSignalHook(endless.PRE_SIGNAL, sig, di.BeginShutdown); err != nil {
+			di.Logger().Error(err.Error())
+		}
+	}
+	if err := srv.ListenAndServe(); err != nil {
 		di.Logger().Error(err.Error())
-		return
 	}
+
+	// 关闭服务
+	di.Shutdown()
 }
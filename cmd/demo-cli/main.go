@@ -4,14 +4,181 @@ package main
 import (
 	"os"
 
+	"go-demo/config"
+	"go-demo/config/di"
 	"go-demo/internal/action"
 
 	"github.com/urfave/cli/v2"
 )
 
+// 迁移命令的数据库名称
+var migrateDBFlag = &cli.StringFlag{Name: "db", Value: "demo", Usage: "数据库名称"}
+
+// 队列命令的过滤条件
+var (
+	queueFlag     = &cli.StringFlag{Name: "queue", Usage: "队列名称, 为空时为全部队列"}
+	queueTypeFlag = &cli.StringFlag{Name: "type", Usage: "任务名, 比如 User:Import"}
+	queueErrFlag  = &cli.StringFlag{Name: "error", Usage: "最近一次失败的原因包含"}
+	queueAllFlag  = &cli.BoolFlag{Name: "all", Usage: "没有 --type, --error 时操作全部任务"}
+)
+
+// queueStateFlag 任务状态, 可以指定多个
+func queueStateFlag(states ...string) *cli.StringSliceFlag {
+	return &cli.StringSliceFlag{Name: "state", Value: cli.NewStringSlice(states...), Usage: "任务状态, pending, active, scheduled, retry, archived, completed"}
+}
+
 func main() {
 	app := &cli.App{
+		Before: func(c *cli.Context) error {
+			// 配置校验, 配置有误程序不允许启动. 配置命令用于生成配置, 不校验
+			if c.Args().First() != "config" {
+				config.MustBind(&config.App)
+			}
+			return nil
+		},
 		Commands: []*cli.Command{ // cli 路由
+			{
+				Name:  "config",
+				Usage: "配置相关",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "gen-key",
+						Usage:  "生成加密密钥的主密钥",
+						Action: action.Config.GenKey,
+					},
+					{
+						Name:      "encrypt",
+						Usage:     "加密密钥, 主密钥为环境变量 CONFIG_MASTER_KEY",
+						ArgsUsage: "<name> [value]",
+						Action:    action.Config.Encrypt,
+					},
+				},
+			},
+			{
+				Name:  "admin",
+				Usage: "管理员相关",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "token",
+						Usage: "签发管理员 JWT, 用于访问 /admin/v1 管理接口",
+						Flags: []cli.Flag{
+							&cli.Int64Flag{Name: "id", Usage: "管理员 ID, 写入审计日志的操作人", Required: true},
+							&cli.StringFlag{Name: "name", Value: "admin", Usage: "管理员名称"},
+						},
+						Action: action.Admin.Token,
+					},
+				},
+			},
+			{
+				Name:  "migrate",
+				Usage: "数据库迁移",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "up",
+						Usage:  "执行未执行的迁移",
+						Flags:  []cli.Flag{migrateDBFlag, &cli.IntFlag{Name: "step", Usage: "执行的版本数, 0 执行全部"}},
+						Action: action.Migrate.Up,
+					},
+					{
+						Name:   "down",
+						Usage:  "回滚最近执行的迁移",
+						Flags:  []cli.Flag{migrateDBFlag, &cli.IntFlag{Name: "step", Value: 1, Usage: "回滚的版本数"}},
+						Action: action.Migrate.Down,
+					},
+					{
+						Name:   "status",
+						Usage:  "迁移状态",
+						Flags:  []cli.Flag{migrateDBFlag},
+						Action: action.Migrate.Status,
+					},
+					{
+						Name:      "create",
+						Usage:     "创建迁移文件, 需要在项目根目录执行",
+						ArgsUsage: "<name>",
+						Flags:     []cli.Flag{migrateDBFlag},
+						Action:    action.Migrate.Create,
+					},
+				},
+			},
+			{
+				Name:  "model",
+				Usage: "表 Model",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "gen",
+						Usage: "从数据库表结构生成 Model, 需要在项目根目录执行",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{Name: "table", Usage: "表名, 可以指定多个", Required: true},
+							&cli.StringFlag{Name: "db", Value: "demo", Usage: "数据库名称"},
+						},
+						Action: action.Model.Gen,
+					},
+				},
+			},
+			{
+				Name:  "queue",
+				Usage: "消息队列管理",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "队列列表, 包括大小, 延迟与今日处理数",
+						Action: action.Queue.List,
+					},
+					{
+						Name:  "tasks",
+						Usage: "任务列表, 包括 payload 与失败原因, 默认为重试与归档的任务",
+						Flags: []cli.Flag{queueFlag, queueStateFlag("retry", "archived"), queueTypeFlag, queueErrFlag,
+							&cli.IntFlag{Name: "limit", Value: 20, Usage: "最多显示的任务数"}},
+						Action: action.Queue.Tasks,
+					},
+					{
+						Name:      "show",
+						Usage:     "任务详情",
+						ArgsUsage: "<id>",
+						Flags:     []cli.Flag{queueFlag},
+						Action:    action.Queue.Show,
+					},
+					{
+						Name:      "retry",
+						Usage:     "立即执行重试, 归档或定时的任务, 按 ID 或过滤条件",
+						ArgsUsage: "[id...]",
+						Flags:     []cli.Flag{queueFlag, queueStateFlag("retry", "archived"), queueTypeFlag, queueErrFlag, queueAllFlag},
+						Action:    action.Queue.Retry,
+					},
+					{
+						Name:      "archive",
+						Usage:     "归档任务, 不再执行, 按 ID 或过滤条件",
+						ArgsUsage: "[id...]",
+						Flags:     []cli.Flag{queueFlag, queueStateFlag("retry"), queueTypeFlag, queueErrFlag, queueAllFlag},
+						Action:    action.Queue.Archive,
+					},
+					{
+						Name:      "delete",
+						Usage:     "删除任务, 按 ID 或过滤条件",
+						ArgsUsage: "[id...]",
+						Flags:     []cli.Flag{queueFlag, queueStateFlag("archived"), queueTypeFlag, queueErrFlag, queueAllFlag},
+						Action:    action.Queue.Delete,
+					},
+					{
+						Name:      "pause",
+						Usage:     "暂停队列",
+						ArgsUsage: "<queue>",
+						Action:    action.Queue.Pause,
+					},
+					{
+						Name:      "unpause",
+						Usage:     "恢复队列",
+						ArgsUsage: "<queue>",
+						Action:    action.Queue.Unpause,
+					},
+					{
+						Name:   "stats",
+						Usage:  "按任务名的处理统计",
+						Flags:  []cli.Flag{&cli.IntFlag{Name: "days", Value: 1, Usage: "最近天数, 包括今天, 1-7"}},
+						Action: action.Queue.Stats,
+					},
+				},
+			},
 			// DEMO
 			{
 				Name:  "user",
@@ -22,12 +189,22 @@ func main() {
 						Usage:  "创建一个用户",
 						Action: action.User.AddUser,
 					},
+					{
+						Name:      "import",
+						Usage:     "从 CSV 或 XLSX 文件导入用户, 需要 demo-queue 运行",
+						ArgsUsage: "<file>",
+						Flags:     []cli.Flag{&cli.BoolFlag{Name: "wait", Value: true, Usage: "等待处理完成并输出进度, --wait=false 只创建导入任务"}},
+						Action:    action.User.Import,
+					},
 				},
 			},
 		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		return
+		di.Logger().Error(err.Error())
 	}
+
+	// 关闭服务
+	di.Shutdown()
 }
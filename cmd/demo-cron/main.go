@@ -2,27 +2,51 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
+	"go-demo/config"
 	"go-demo/config/di"
 	"go-demo/internal/cron"
+	"go-demo/pkg/gox"
+	"go-demo/pkg/lifecycle"
 
 	"github.com/go-co-op/gocron/v2"
 )
 
 func main() {
+	// 配置校验, 配置有误程序不允许启动
+	config.MustBind(&config.App)
+
+	// 配置热更新
+	di.WatchConfig()
+
+	// 健康检查
+	healthServer := &http.Server{
+		Addr:    fmt.Sprintf(":%d", config.GetInt("cron_health_port")),
+		Handler: di.Health().ServeMux(),
+	}
+	di.Lifecycle().Register(lifecycle.StageServer, "health", healthServer.Shutdown)
+	gox.SafeGo(func() {
+		if err := healthServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			di.Logger().Error(err.Error())
+		}
+	})
+
 	// create a scheduler
-	s, err := gocron.NewScheduler()
+	// 停止时等待执行中的任务完成, 超时不再等待
+	s, err := gocron.NewScheduler(gocron.WithStopTimeout(di.ShutdownTimeout()))
 	if err != nil {
 		di.Logger().Error(err.Error())
 		return
 	}
-	defer func() {
+	di.Lifecycle().Register(lifecycle.StageServer, "cron", func(ctx context.Context) error {
 		// when you're done, shut it down
-		if err := s.Shutdown(); err != nil {
-			di.Logger().Error(err.Error())
-		}
-	}()
+		return s.Shutdown()
+	})
 
 	// add a job to the scheduler
 	if _, err := s.NewJob(
@@ -36,5 +60,10 @@ func main() {
 	s.Start()
 
 	// block until you are ready to shut down
-	select {}
+	ctx, stop := lifecycle.SignalContext()
+	defer stop()
+	<-ctx.Done()
+
+	// 关闭服务
+	di.Shutdown()
 }
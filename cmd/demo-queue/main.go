@@ -3,11 +3,24 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"go-demo/config"
 	"go-demo/config/di"
+	"go-demo/internal/consts"
+	"go-demo/internal/service"
 	"go-demo/internal/task"
+	"go-demo/internal/types"
+	"go-demo/pkg/gox"
+	"go-demo/pkg/lifecycle"
+	"go-demo/pkg/queuex"
 
 	"github.com/hibiken/asynq"
 )
@@ -28,16 +41,65 @@ func loggingMiddleware(h asynq.Handler) asynq.Handler {
 }
 
 func main() {
+	// 配置校验, 配置有误程序不允许启动
+	config.MustBind(&config.App)
+
+	// 配置热更新
+	di.WatchConfig()
+
+	// 链路追踪
+	di.TracerProvider()
+
+	// 诊断服务
+	di.ServeDiag(config.GetInt("queue_diag_port"), service.Auth.AdminRequest)
+
+	// 健康检查
+	healthServer := &http.Server{
+		Addr:    fmt.Sprintf(":%d", config.GetInt("queue_health_port")),
+		Handler: di.Health().ServeMux(),
+	}
+	di.Lifecycle().Register(lifecycle.StageServer, "health", healthServer.Shutdown)
+	gox.SafeGo(func() {
+		if err := healthServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			di.Logger().Error(err.Error())
+		}
+	})
+
 	// mux maps a type to a handler
 	mux := asynq.NewServeMux()
-	mux.Use(loggingMiddleware)
+	mux.Use(queuex.TracingMiddleware, queuex.StatsMiddleware(di.StorageRedis(), consts.QueueStats), loggingMiddleware)
 
 	// register handler DEMO
-	mux.HandleFunc("User:AddUser", task.User.AddUser)
+	types.UserAddTask.Handle(mux, task.User.AddUser)
+	types.UserImportTask.Handle(mux, task.User.Import)
+	di.AuditTask.Handle(mux, task.Audit.Write)
+
+	ctx, stop := lifecycle.SignalContext()
+	defer stop()
 
 	// run queue server
-	if err := di.QueueServer().Run(mux); err != nil {
+	// 收到 SIGINT/SIGTERM 时先使就绪检查失败, 再由 di.Shutdown 停止投递, 等待处理中的任务完成
+	if err := di.QueueServer().Start(mux); err != nil {
 		di.Logger().Error(err.Error())
-		return
+		stop()
 	}
+	// 收到 SIGTSTP 停止拉取新任务
+	tstp := make(chan os.Signal, 1)
+	signal.Notify(tstp, syscall.SIGTSTP)
+	gox.SafeGo(func() {
+		for range tstp {
+			di.QueueServer().Stop()
+		}
+	})
+
+	// 事务发件箱投递, 在 Worker 之后注册, 关闭时先停止投递
+	di.StartOutboxRelay()
+
+	// 等待退出信号
+	<-ctx.Done()
+	signal.Stop(tstp)
+	di.BeginShutdown()
+
+	// 关闭服务
+	di.Shutdown()
 }
@@ -4,17 +4,20 @@ package main
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"go-demo/config"
 	"go-demo/config/di"
 	"go-demo/internal/consts"
 	"go-demo/internal/service"
 	"go-demo/internal/types"
 	"go-demo/internal/ws"
 	"go-demo/pkg/gox"
+	"go-demo/pkg/lifecycle"
 
 	"github.com/goccy/go-json"
 	"github.com/gorilla/websocket"
@@ -39,6 +42,7 @@ func socketHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	client.Conn = conn
 	client.IsClosed = false
+	service.WS.Add(client)
 	// Close
 	defer service.WS.Close(client)
 
@@ -104,6 +108,11 @@ func socketHandler(w http.ResponseWriter, r *http.Request) {
 	// 向频道发送消息的格式为 json 字符串 `{"user_id": int, "type": string, data: {}}`
 	// user_id 为 0 表示向所有用户推送消息, 否则为向指定用户推送消息
 	pubsub := di.StorageRedis().Subscribe(context.Background(), "WSMessageChannel") // 订阅一个或多个频道
+	defer func() {
+		if err := pubsub.Close(); err != nil {
+			di.Logger().Error(err.Error())
+		}
+	}()
 	// 检查订阅是否成功
 	if _, err := pubsub.Receive(context.Background()); err != nil {
 		di.Logger().Error(err.Error())
@@ -172,9 +181,38 @@ func socketHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
-	http.HandleFunc("/websocket", socketHandler)
-	if err := http.ListenAndServe(":9090", nil); err != nil {
-		di.Logger().Error(err.Error())
-		return
-	}
+	// 配置校验, 配置有误程序不允许启动
+	config.MustBind(&config.App)
+
+	// 配置热更新
+	di.WatchConfig()
+
+	ctx, stop := lifecycle.SignalContext()
+	defer stop()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/websocket", socketHandler)
+	mux.Handle("/healthz", di.Health().LivenessHandler()) // 存活
+	mux.Handle("/readyz", di.Health().ReadinessHandler()) // 就绪
+	srv := &http.Server{Addr: ":9090", Handler: mux}
+	// 停止接收新连接, 再通知在线的 client 断开
+	di.Lifecycle().Register(lifecycle.StageServer, "websocket", func(ctx context.Context) error {
+		if err := srv.Shutdown(ctx); err != nil {
+			return err
+		}
+		return service.WS.Shutdown(ctx)
+	})
+	gox.SafeGo(func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			di.Logger().Error(err.Error())
+			stop()
+		}
+	})
+
+	// 等待退出信号
+	<-ctx.Done()
+	di.BeginShutdown()
+
+	// 关闭服务
+	di.Shutdown()
 }
@@ -0,0 +1,155 @@
+// Package config 配置实现
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// AppConfig 应用配置声明
+//
+//	声明配置项的类型, 默认值与校验规则, 新增配置项时在此声明. 各入口启动时调用 MustBind(&App) 校验, 配置有误程序不允许启动.
+//	默认值同时对 GetInt(), GetString() 等函数生效, 优先级低于 common 配置.
+//	标记 hot:"true" 的配置项支持热更新, 使用方需通过 OnChange() 订阅变化; 其他配置项修改后需要重启生效.
+type AppConfig struct {
+	Log struct {
+		Path        string `config:"error_log"`
+		Level       string `config:"error_log_level" hot:"true" default:"Error" validate:"oneof=Debug Info Warn Error"`
+		Stdout      bool   `config:"error_log_stdout"`
+		MaxSize     int    `config:"error_log_max_size" default:"100" validate:"min=1"`
+		MaxBackups  int    `config:"error_log_max_backups" default:"10" validate:"min=0"`
+		MaxAge      int    `config:"error_log_max_age" default:"30" validate:"min=0"`
+		Compress    bool   `config:"error_log_compress"`
+		RotateDaily bool   `config:"error_log_rotate_daily"`
+	}
+
+	ConfigWatchInterval time.Duration `config:"config_watch_interval" default:"10" validate:"min=0s"`
+	ConfigRedisKey      string        `config:"config_redis_key"`
+
+	WorkerPool       int               `config:"worker_pool" hot:"true" default:"409600" validate:"min=1"`
+	QPSLimit         int               `config:"qps_limit" hot:"true" default:"40000" validate:"min=1"`
+	Timeout          time.Duration     `config:"timeout" hot:"true" default:"30" validate:"min=1s"`
+	ShutdownTimeout  time.Duration     `config:"shutdown_timeout" default:"30" validate:"min=1s"`
+	ShutdownDelay    time.Duration     `config:"shutdown_delay" default:"0" validate:"min=0s"`
+	EncryptedSecrets map[string]string `config:"encrypted_secrets"`
+	ServerPort       int               `config:"server_port" validate:"required,min=1,max=65535"`
+	JWTSecret        string            `config:"jwt_secret" validate:"required,min=32"`
+
+	Health struct {
+		QueuePort int `config:"queue_health_port" default:"9091" validate:"min=1,max=65535"`
+		CronPort  int `config:"cron_health_port" default:"9092" validate:"min=1,max=65535"`
+	}
+
+	Diag struct {
+		APIPort   int      `config:"api_diag_port" validate:"min=0,max=65535"`
+		QueuePort int      `config:"queue_diag_port" validate:"min=0,max=65535"`
+		AllowIPs  []string `config:"diag_allow_ips"`
+	}
+
+	Trace struct {
+		Exporter      string  `config:"trace_exporter" default:"off" validate:"oneof=otlp stdout off"`
+		OTLPEndpoint  string  `config:"trace_otlp_endpoint"`
+		OTLPInsecure  bool    `config:"trace_otlp_insecure"`
+		SamplePercent float64 `config:"trace_sample_percent" default:"100" validate:"min=0,max=100"`
+	}
+
+	Queue struct {
+		Queues      map[string]int `config:"queues" validate:"required"`                       // 队列名称与权重, 见 queuex.QueueDefault
+		Concurrency int            `config:"queue_concurrency" default:"100" validate:"min=1"` // Worker 并发数
+
+		OutboxInterval  time.Duration `config:"outbox_poll_interval" default:"1" validate:"min=100ms"` // 发件箱轮询间隔
+		OutboxRetention time.Duration `config:"outbox_retention" default:"86400" validate:"min=1s"`    // 发件箱已投递的行保留时间
+	}
+
+	Databases []string `config:"databases" validate:"required"` // 数据库名称, 每个数据库的配置项为 db_<name>_<key>, 见 DBConfig
+
+	Redis struct {
+		Host         string `config:"redis_host" validate:"required"`
+		Port         int    `config:"redis_port" default:"6379" validate:"min=1,max=65535"`
+		Auth         string `config:"redis_auth"`
+		IndexCache   int    `config:"redis_index_cache" validate:"min=0,max=15"`
+		IndexJWT     int    `config:"redis_index_jwt" validate:"min=0,max=15"`
+		IndexStorage int    `config:"redis_index_storage" validate:"min=0,max=15"`
+		IndexQueue   int    `config:"redis_index_queue" validate:"min=0,max=15"`
+	}
+}
+
+func (c *AppConfig) validate(get func(key string) (any, error)) ValidationError {
+	var errs ValidationError
+	if _, ok := c.Queue.Queues["default"]; !ok {
+		errs = append(errs, "queues: 缺少 default 队列")
+	}
+	for name, weight := range c.Queue.Queues {
+		if weight < 1 {
+			errs = append(errs, fmt.Sprintf("queues: 队列 %s 的权重 %d 不能小于 1", name, weight))
+		}
+	}
+	for _, name := range c.Databases {
+		if err := bind(&DBConfig{prefix: DBKeyPrefix(name)}, DBKeyPrefix(name), get); err != nil {
+			errs = append(errs, err.(ValidationError)...)
+		}
+	}
+
+	return errs
+}
+
+// DBConfig 数据库配置
+//
+//	配置项为 db_<name>_<key>, 比如 db_demo_host, 数据库名称在 databases 中声明.
+type DBConfig struct {
+	Driver       string `config:"driver" default:"mysql" validate:"oneof=mysql sqlite"` // 数据库驱动, sqlite 只需配置 dbname 为文件路径
+	Host         string `config:"host"`                                                 // MySQL 必填
+	Port         int    `config:"port" default:"3306" validate:"min=1,max=65535"`
+	Username     string `config:"username"` // MySQL 必填
+	Password     string `config:"password"`
+	DBName       string `config:"dbname" validate:"required"`
+	Charset      string `config:"charset" default:"utf8mb4"`
+	MaxOpenConns int    `config:"max_open_conns" hot:"true" validate:"required,min=1"`
+	MaxIdleConns int    `config:"max_idle_conns" hot:"true" validate:"required,min=0"`
+
+	Replicas      []string `config:"replicas"` // 从库 host:port, 与主库使用相同的账号与库名
+	ReplicaPolicy string   `config:"replica_policy" default:"random" validate:"oneof=random round_robin least_conn"`
+
+	AuditTables      []string `config:"audit_tables"`       // 审计的表, 记录增删改
+	AuditMaskColumns []string `config:"audit_mask_columns"` // 审计时脱敏的列
+
+	prefix string
+}
+
+func (c *DBConfig) validate(get func(key string) (any, error)) ValidationError {
+	var errs ValidationError
+	if c.Driver == "mysql" {
+		if c.Host == "" {
+			errs = append(errs, c.prefix+"host: 缺少配置")
+		}
+		if c.Username == "" {
+			errs = append(errs, c.prefix+"username: 缺少配置")
+		}
+	}
+
+	return errs
+}
+
+// 数据库配置项前缀
+const dbKeyPrefix = "db_"
+
+// DBKeyPrefix 数据库配置项前缀, db_<name>_
+func DBKeyPrefix(name string) string {
+	return dbKeyPrefix + name + "_"
+}
+
+// DB 获取数据库配置
+func DB(name string) (DBConfig, error) {
+	dbConfig := DBConfig{prefix: DBKeyPrefix(name)}
+	if !lo.Contains(GetStringSlice("databases"), name) {
+		return dbConfig, fmt.Errorf("数据库 %s 未在 databases 中声明", name)
+	}
+	err := BindPrefix(DBKeyPrefix(name), &dbConfig)
+
+	return dbConfig, err
+}
+
+// App 应用配置, MustBind(&App) 后可用. 为启动时的配置, 不随热更新变化, 支持热更新的配置项使用 GetInt() 等函数获取
+var App AppConfig
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAppConfigValidateQueues(t *testing.T) {
+	tests := []struct {
+		name    string
+		queues  map[string]int
+		wantErr ValidationError
+	}{
+		{name: "正常", queues: map[string]int{"default": 9, "low": 1}},
+		{name: "缺少 default", queues: map[string]int{"low": 1}, wantErr: ValidationError{"queues: 缺少 default 队列"}},
+		{name: "权重小于 1", queues: map[string]int{"default": 0}, wantErr: ValidationError{"queues: 队列 default 的权重 0 不能小于 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c AppConfig
+			c.Queue.Queues = tt.queues
+			err := c.validate(func(string) (any, error) { return nil, nil })
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+// Package config 配置实现
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+)
+
+// ValidationError 配置校验错误, 包含全部缺失或无效的配置项
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "配置校验失败:\n  - " + strings.Join(e, "\n  - ")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Bind 将配置绑定到结构体
+//
+//	结构体字段标签:
+//	config:"redis_host"             配置键名, 无此标签的结构体字段递归绑定, 用于分组
+//	default:"127.0.0.1"             默认值, 配置缺失时使用
+//	validate:"required,min=1"       校验规则, 逗号分隔
+//
+//	校验规则:
+//	required                        必填, 配置缺失或为空字符串不通过
+//	min=n, max=n                    数值比较大小, 字符串, 切片比较长度, time.Duration 比较时长, 如 min=1s
+//	oneof=a b c                     枚举, 空格分隔
+//
+//	支持 string, bool, 整数, 浮点数, time.Duration, []string, []int, map[string]string, map[string]int 类型.
+//	不会在第一个错误处停止, 返回的 ValidationError 包含全部缺失或无效的配置项.
+func Bind(dst any) error {
+	return BindPrefix("", dst)
+}
+
+// BindPrefix 将前缀相同的一组配置绑定到结构体, 配置键名为 prefix + config 标签
+//
+//	用于按名称声明的同类配置, 比如 db_demo_host, db_demo_port 使用前缀 db_demo_ 绑定到 DBConfig.
+func BindPrefix(prefix string, dst any) error {
+	layers := *configure.Load()
+	return bind(dst, prefix, func(key string) (any, error) {
+		return resolveSecret(layers, getFrom(layers, key), true)
+	})
+}
+
+// validator 绑定后的额外校验
+type validator interface {
+	validate(get func(key string) (any, error)) ValidationError
+}
+
+func bind(dst any, prefix string, get func(key string) (any, error)) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return errors.New("config.Bind 参数必须为结构体指针")
+	}
+
+	var errs ValidationError
+	bindStruct(v.Elem(), prefix, get, &errs)
+	if dstValidator, ok := dst.(validator); ok && len(errs) == 0 {
+		errs = append(errs, dstValidator.validate(get)...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// MustBind 绑定配置, 失败输出全部错误后退出
+//
+//	各入口启动时最先调用, 配置有误程序不允许启动.
+func MustBind(dst any) {
+	if err := Bind(dst); err != nil {
+		zap.L().Error(err.Error())
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func bindStruct(v reflect.Value, prefix string, get func(key string) (any, error), errs *ValidationError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := field.Tag.Get("config")
+		if key == "" {
+			if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+				bindStruct(v.Field(i), prefix, get, errs)
+			}
+			continue
+		}
+
+		key = prefix + key
+		value, err := get(key)
+		if err != nil {
+			*errs = append(*errs, key+": "+err.Error())
+			continue
+		}
+		if isEmpty(value) {
+			if defaultValue, ok := field.Tag.Lookup("default"); ok {
+				value = defaultValue
+			}
+		}
+		rules := parseRules(field.Tag.Get("validate"))
+		if isEmpty(value) {
+			if _, ok := rules["required"]; ok {
+				*errs = append(*errs, key+": 缺少配置")
+			}
+			continue
+		}
+
+		if err := setValue(v.Field(i), value); err != nil {
+			*errs = append(*errs, fmt.Sprintf("%s: 值 %v 无法转换为 %s", key, value, field.Type))
+			continue
+		}
+		for _, msg := range checkRules(v.Field(i), rules) {
+			*errs = append(*errs, key+": "+msg)
+		}
+	}
+}
+
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	return ok && s == ""
+}
+
+func setValue(field reflect.Value, value any) error {
+	if field.Type() == durationType {
+		d, err := toDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		s, err := cast.ToStringE(value)
+		if err != nil {
+			return err
+		}
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := cast.ToBoolE(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := cast.ToInt64E(value)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(n) {
+			return errors.New("溢出")
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := cast.ToUint64E(value)
+		if err != nil {
+			return err
+		}
+		if field.OverflowUint(n) {
+			return errors.New("溢出")
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := cast.ToFloat64E(value)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		switch field.Type().Elem().Kind() {
+		case reflect.String:
+			s, err := cast.ToStringSliceE(value)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(s))
+		case reflect.Int:
+			s, err := cast.ToIntSliceE(value)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(s))
+		default:
+			return errors.New("不支持的类型")
+		}
+	case reflect.Map:
+		switch field.Type().Elem().Kind() {
+		case reflect.String:
+			m, err := cast.ToStringMapStringE(value)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(m))
+		case reflect.Int:
+			m, err := cast.ToStringMapIntE(value)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(m))
+		default:
+			return errors.New("不支持的类型")
+		}
+	default:
+		return errors.New("不支持的类型")
+	}
+
+	return nil
+}
+
+// toDuration 转换为时长
+//
+//	数值按秒计算, 与已有的 timeout, shutdown_timeout 等配置一致; 字符串支持 time.ParseDuration 格式, 如 1m30s.
+func toDuration(value any) (time.Duration, error) {
+	if s, ok := value.(string); ok {
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return time.ParseDuration(s)
+		}
+	}
+	seconds, err := cast.ToFloat64E(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func parseRules(tag string) map[string]string {
+	rules := map[string]string{}
+	for _, rule := range strings.Split(tag, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		name, param, _ := strings.Cut(rule, "=")
+		rules[name] = param
+	}
+
+	return rules
+}
+
+func checkRules(field reflect.Value, rules map[string]string) []string {
+	msgs := make([]string, 0)
+	for _, name := range []string{"min", "max", "oneof"} {
+		param, ok := rules[name]
+		if !ok {
+			continue
+		}
+		if name == "oneof" {
+			options := strings.Fields(param)
+			value := fmt.Sprint(field.Interface())
+			if !lo.Contains(options, value) {
+				msgs = append(msgs, fmt.Sprintf("值 %s 无效, 只能为 %s 之一", value, strings.Join(options, ", ")))
+			}
+			continue
+		}
+
+		current, limit, err := compareValues(field, param)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("校验规则 %s=%s 无效", name, param))
+			continue
+		}
+		if name == "min" && current < limit {
+			msgs = append(msgs, fmt.Sprintf("值 %v 不能小于 %s", field.Interface(), param))
+		}
+		if name == "max" && current > limit {
+			msgs = append(msgs, fmt.Sprintf("值 %v 不能大于 %s", field.Interface(), param))
+		}
+	}
+
+	return msgs
+}
+
+// compareValues 返回字段与规则参数可比较的值, 字符串, 切片, map 比较长度
+func compareValues(field reflect.Value, param string) (float64, float64, error) {
+	if field.Type() == durationType {
+		limit, err := toDuration(param)
+		return float64(field.Int()), float64(limit), err
+	}
+	limit, err := strconv.ParseFloat(param, 64)
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), limit, err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), limit, err
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), limit, err
+	case reflect.String, reflect.Slice, reflect.Map:
+		return float64(field.Len()), limit, err
+	default:
+		return 0, 0, errors.New("不支持的类型")
+	}
+}
+
+// walkTags 遍历结构体中声明的配置项
+func walkTags(t reflect.Type, f func(key string, tag reflect.StructTag)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("config")
+		if key == "" {
+			if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+				walkTags(field.Type, f)
+			}
+			continue
+		}
+		f(key, field.Tag)
+	}
+}
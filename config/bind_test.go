@@ -0,0 +1,113 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type bindTestConfig struct {
+	Host    string         `config:"host" default:"127.0.0.1"`
+	Port    int            `config:"port" validate:"required,min=1,max=65535"`
+	Debug   bool           `config:"debug"`
+	Timeout time.Duration  `config:"timeout" default:"30" validate:"min=1s"`
+	Mode    string         `config:"mode" default:"random" validate:"oneof=random round_robin"`
+	Tags    []string       `config:"tags"`
+	Weights map[string]int `config:"weights"`
+
+	Redis struct {
+		Index int `config:"redis_index" validate:"min=0,max=15"`
+	}
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]any
+		want    func(c *bindTestConfig)
+		wantErr ValidationError
+	}{
+		{
+			name:   "默认值",
+			values: map[string]any{"port": 8080},
+			want: func(c *bindTestConfig) {
+				c.Host, c.Port, c.Timeout, c.Mode = "127.0.0.1", 8080, 30*time.Second, "random"
+			},
+		},
+		{
+			name: "类型转换",
+			values: map[string]any{"host": "db", "port": "3306", "debug": "true", "timeout": "1m30s",
+				"mode": "round_robin", "tags": []any{"a", "b"}, "weights": map[string]any{"default": 9, "low": "1"}, "redis_index": 3},
+			want: func(c *bindTestConfig) {
+				c.Host, c.Port, c.Debug, c.Timeout, c.Mode, c.Tags = "db", 3306, true, 90*time.Second, "round_robin", []string{"a", "b"}
+				c.Weights = map[string]int{"default": 9, "low": 1}
+				c.Redis.Index = 3
+			},
+		},
+		{
+			name:   "时长按秒",
+			values: map[string]any{"port": 1, "timeout": 1.5},
+			want: func(c *bindTestConfig) {
+				c.Host, c.Port, c.Timeout, c.Mode = "127.0.0.1", 1, 1500*time.Millisecond, "random"
+			},
+		},
+		{
+			name:    "缺少必填",
+			values:  map[string]any{"port": ""},
+			wantErr: ValidationError{"port: 缺少配置"},
+		},
+		{
+			name:   "返回全部错误",
+			values: map[string]any{"port": 70000, "timeout": "0.5", "mode": "least", "redis_index": "x"},
+			wantErr: ValidationError{
+				"port: 值 70000 不能大于 65535",
+				"timeout: 值 500ms 不能小于 1s",
+				"mode: 值 least 无效, 只能为 random, round_robin 之一",
+				"redis_index: 值 x 无法转换为 int",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bindTestConfig
+			err := bind(&got, "", func(key string) (any, error) {
+				return tt.values[key], nil
+			})
+			if tt.wantErr != nil {
+				if !reflect.DeepEqual(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want bindTestConfig
+			tt.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestBindPrefix(t *testing.T) {
+	values := map[string]any{"db_demo_port": 3306, "db_demo_host": "demo"}
+	var got bindTestConfig
+	if err := bind(&got, "db_demo_", func(key string) (any, error) {
+		return values[key], nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Host != "demo" || got.Port != 3306 {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestBindInvalidDst(t *testing.T) {
+	var c bindTestConfig
+	if err := bind(c, "", func(string) (any, error) { return nil, nil }); err == nil {
+		t.Error("非指针参数应返回错误")
+	}
+}
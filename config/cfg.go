@@ -3,14 +3,28 @@ package config
 
 import (
 	"os"
+	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/samber/lo"
 	"github.com/spf13/cast"
 	"go.uber.org/zap"
 )
 
-// configure{"common": map[string]any, "<runtimeEnv>": map[string]any}
-var configure = map[string]map[string]any{}
+// EnvPrefix 环境变量覆盖配置的前缀, 比如 APP_REDIS_HOST 覆盖 redis_host
+const EnvPrefix = "APP_"
+
+// 当前生效的配置层, 优先级从低到高: default, common, <runtimeEnv>, local, 其他配置源. 热更新时整体替换
+var configure atomic.Pointer[[]Layer]
+
+func init() {
+	providers = []Provider{NewFileProvider(0)}
+	layers, err := loadLayers(providers)
+	if err != nil { // 配置加载失败, 程序不允许启动
+		panic(err)
+	}
+	configure.Store(&layers)
+}
 
 // RuntimeEnv 获取运行时环境
 func RuntimeEnv() string {
@@ -22,31 +36,71 @@ func RuntimeEnv() string {
 	return runtimeEnv
 }
 
-// EnvCheck 环境校验
-func EnvCheck(env string) bool {
-	return lo.Contains([]string{RuntimeEnv(), "common", "local"}, env)
+func get(key string) any {
+	layers := *configure.Load()
+	value, err := resolveSecret(layers, getFrom(layers, key), true)
+	if err != nil {
+		zap.L().Error(err.Error())
+	}
+
+	return value
 }
 
-func get(key string) any {
-	runtimeEnv := RuntimeEnv()
+func getFrom(layers []Layer, key string) any {
+	var value any
+	for _, layer := range layers { // 高优先级覆盖低优先级
+		if layerValue, ok := layer.Values[key]; ok {
+			value = layerValue
+		}
+	}
+
+	// 环境变量优先级最高
+	if envValue, ok := os.LookupEnv(EnvPrefix + strings.ToUpper(key)); ok {
+		return fromEnv(envValue, value)
+	}
+
+	return value
+}
 
-	if localConfigure, ok := configure["local"]; ok { // local 配置
-		if value, ok := localConfigure[key]; ok {
-			return value
+// fromEnv 环境变量值转换
+//
+//	环境变量只能是字符串, 原配置为切片时按逗号分隔.
+func fromEnv(envValue string, value any) any {
+	switch value.(type) {
+	case []any, []string, []int:
+		if envValue == "" {
+			return []string{}
 		}
+		return strings.Split(envValue, ",")
 	}
-	if envConfigure, ok := configure[runtimeEnv]; ok { // 环境配置
-		if value, ok := envConfigure[key]; ok {
-			return value
+
+	return envValue
+}
+
+// All 获取当前环境生效的全部配置
+//
+//	同键名按 环境变量 > 其他配置源 > local > 环境 > common > 默认值 的优先级合并.
+func All() map[string]any {
+	return allFrom(*configure.Load())
+}
+
+func allFrom(layers []Layer) map[string]any {
+	all := map[string]any{}
+	for _, layer := range layers {
+		for k, v := range layer.Values {
+			all[k] = v
 		}
 	}
-	if commonConfigure, ok := configure["common"]; ok { // 公共配置
-		if value, ok := commonConfigure[key]; ok {
-			return value
+	for _, env := range os.Environ() {
+		name, envValue, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
 		}
+		key := strings.ToLower(strings.TrimPrefix(name, EnvPrefix))
+		all[key] = fromEnv(envValue, all[key])
 	}
 
-	return nil
+	return all
 }
 
 func GetInt(key string) int {
@@ -65,6 +119,29 @@ func GetString(key string) string {
 	return value
 }
 
+func GetFloat64(key string) float64 {
+	value, err := cast.ToFloat64E(get(key))
+	if err != nil {
+		zap.L().Error(err.Error())
+	}
+	return value
+}
+
+// GetDuration 获取时长
+//
+//	数值按秒计算, 比如 30; 字符串支持 time.ParseDuration 格式, 比如 1m30s.
+func GetDuration(key string) time.Duration {
+	value := get(key)
+	if value == nil {
+		return 0
+	}
+	duration, err := toDuration(value)
+	if err != nil {
+		zap.L().Error(err.Error())
+	}
+	return duration
+}
+
 func GetBool(key string) bool {
 	value, err := cast.ToBoolE(get(key))
 	if err != nil {
@@ -96,3 +173,11 @@ func GetStringMapString(key string) map[string]string {
 	}
 	return value
 }
+
+func GetStringMapInt(key string) map[string]int {
+	value, err := cast.ToStringMapIntE(get(key))
+	if err != nil {
+		zap.L().Error(err.Error())
+	}
+	return value
+}
@@ -0,0 +1,35 @@
+package di
+
+import (
+	"context"
+
+	"go-demo/pkg/gormx"
+	"go-demo/pkg/queuex"
+
+	"github.com/samber/lo"
+)
+
+// AuditTask 审计记录写入任务, 发送到低优先级队列, demo-queue 写入 t_audit_logs
+var AuditTask = queuex.TaskDef[AuditPayload]{Name: "Audit:Write", Queue: queuex.QueueLow}
+
+// AuditPayload 审计记录写入任务的 payload
+type AuditPayload struct {
+	Records []gormx.AuditRecord `json:"records"`
+}
+
+// 每个任务的审计记录数
+const auditBatchSize = 100
+
+// Audit 写入审计记录
+//
+//	发送低优先级任务异步写入, 请求结束后 ctx 被取消也会发送.
+func Audit(ctx context.Context, records []gormx.AuditRecord) error {
+	ctx = context.WithoutCancel(ctx)
+	for _, chunk := range lo.Chunk(records, auditBatchSize) {
+		if err := AuditTask.Enqueue(ctx, QueueClient(), AuditPayload{Records: chunk}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
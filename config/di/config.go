@@ -0,0 +1,40 @@
+// Package di 服务注入
+package di
+
+import (
+	"context"
+	"sync"
+
+	"go-demo/config"
+	"go-demo/pkg/lifecycle"
+)
+
+var watchConfigOnce sync.Once
+
+// WatchConfig 监听配置变化, 热更新
+//
+//	外部配置目录 CONFIG_DIR 按 config_watch_interval 轮询; config_redis_key 不为空时将存储 redis 中该 Hash 作为配置源, 优先级高于配置文件.
+//	各常驻进程入口启动时调用, 热更新的配置项通过 config.OnChange() 订阅.
+func WatchConfig() {
+	watchConfigOnce.Do(func() {
+		interval := config.GetDuration("config_watch_interval")
+		if redisKey := config.GetString("config_redis_key"); redisKey != "" {
+			if _, err := config.AddProvider(&config.KVProvider{
+				Name: "redis",
+				Fetch: func(ctx context.Context) (map[string]string, error) {
+					return StorageRedis().HGetAll(ctx, redisKey).Result()
+				},
+				Interval: interval,
+			}); err != nil {
+				Logger().Error(err.Error())
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		config.Watch(ctx, interval)
+		Lifecycle().Register(lifecycle.StageServer, "config:watch", func(ctx context.Context) error {
+			cancel()
+			return nil
+		})
+	})
+}
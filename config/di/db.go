@@ -1,35 +1,154 @@
 package di
 
 import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
 	"go-demo/config"
 	"go-demo/pkg/gormx"
 	"go-demo/pkg/gox"
+	"go-demo/pkg/lifecycle"
 
+	"github.com/spf13/cast"
 	"gorm.io/gorm"
 )
 
-/**************** DEMO DB *************************************************/
+// 已声明的数据库, 第一次使用时连接
 var (
-	demoDB     *gorm.DB
-	demoDBOnce gox.Once
+	dbs     = map[string]*gorm.DB{}
+	dbOnces = map[string]*gox.Once{}
+	dbsMu   sync.RWMutex
 )
 
-func DemoDB() *gorm.DB {
-	_ = demoDBOnce.Do(func() (err error) {
-		demoDB, err = gormx.NewDB(gormx.NewDBReq{
-			LogLevel:     config.GetString("error_log_level"),
-			UserName:     config.GetString("mysql_username"),
-			Password:     config.GetString("mysql_password"),
-			Host:         config.GetString("mysql_host"),
-			Port:         config.GetInt("mysql_port"),
-			DBName:       config.GetString("mysql_dbname"),
-			Charset:      config.GetString("mysql_charset"),
-			MaxIdleConns: config.GetInt("mysql_max_idle_conns"),
-			MaxOpenConns: config.GetInt("mysql_max_open_conns"),
+// DB 按名称获取数据库
+//
+//	数据库在 databases 配置中声明, 配置项为 db_<name>_<key>, 见 config.DBConfig. 连接失败返回 nil, 下次调用时重试.
+//	第一次使用时注册健康检查, 只检查进程用到的数据库.
+func DB(name string) *gorm.DB {
+	dbsMu.Lock()
+	once, ok := dbOnces[name]
+	if !ok {
+		once = &gox.Once{}
+		dbOnces[name] = once
+		Health().Register("mysql:"+name, func(ctx context.Context) error {
+			return pingDB(ctx, name)
 		})
+	}
+	dbsMu.Unlock()
 
+	_ = once.Do(func() error {
+		return openDB(name)
+	})
+
+	dbsMu.RLock()
+	defer dbsMu.RUnlock()
+	return dbs[name]
+}
+
+// SetDB 注入数据库, 用于测试
+//
+//	注入后 DB(name) 返回 db, 不再按配置连接, 比如 gormx.NewMemoryDB 创建的 SQLite 内存数据库.
+//	db 为 nil 时清除注入, 下次调用 DB(name) 时按配置连接. 不负责关闭 db.
+func SetDB(name string, db *gorm.DB) {
+	dbsMu.Lock()
+	defer dbsMu.Unlock()
+	if db == nil {
+		delete(dbs, name)
+		delete(dbOnces, name)
 		return
+	}
+	once := &gox.Once{}
+	_ = once.Do(func() error { return nil })
+	dbs[name] = db
+	dbOnces[name] = once
+}
+
+// DemoDB DEMO 数据库, 即 DB("demo")
+func DemoDB() *gorm.DB {
+	return DB("demo")
+}
+
+// pingDB 检查数据库连接, 连接失败时重新连接
+func pingDB(ctx context.Context, name string) error {
+	db := DB(name)
+	if db == nil {
+		return errors.New("连接失败")
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func openDB(name string) error {
+	dbConfig, err := config.DB(name)
+	if err != nil {
+		Logger().Error(err.Error())
+		return err
+	}
+
+	// 从库, 与主库使用相同的账号与库名
+	replicas := make([]string, 0, len(dbConfig.Replicas))
+	for _, addr := range dbConfig.Replicas {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			Logger().Error(err.Error())
+			return err
+		}
+		replicas = append(replicas, gormx.MySQLDSN(dbConfig.Username, dbConfig.Password, host, cast.ToInt(port), dbConfig.DBName, dbConfig.Charset))
+	}
+
+	db, err := gormx.NewDB(gormx.NewDBReq{
+		Driver:        dbConfig.Driver,
+		LogLevel:      config.GetString("error_log_level"),
+		UserName:      dbConfig.Username,
+		Password:      dbConfig.Password,
+		Host:          dbConfig.Host,
+		Port:          dbConfig.Port,
+		DBName:        dbConfig.DBName,
+		Charset:       dbConfig.Charset,
+		MaxIdleConns:  dbConfig.MaxIdleConns,
+		MaxOpenConns:  dbConfig.MaxOpenConns,
+		Replicas:      replicas,
+		ReplicaPolicy: dbConfig.ReplicaPolicy,
+		CacheRedis:    CacheRedis(),
+		CachePrefix:   "gormx:" + name + ":",
+		Audit: gormx.AuditConfig{
+			Tables:      dbConfig.AuditTables,
+			MaskColumns: dbConfig.AuditMaskColumns,
+			Sink:        Audit,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	dbsMu.Lock()
+	dbs[name] = db
+	dbsMu.Unlock()
+
+	label := "mysql:" + name
+	Diag().RegisterStats(label, func() any {
+		return gormx.Stats(db)
+	})
+	// 连接池大小热更新
+	setPool := func() {
+		dbConfig, err := config.DB(name)
+		if err != nil {
+			Logger().Error(err.Error())
+			return
+		}
+		if err := gormx.SetPool(db, dbConfig.MaxIdleConns, dbConfig.MaxOpenConns); err != nil {
+			Logger().Error(err.Error())
+		}
+	}
+	config.OnChange(config.DBKeyPrefix(name)+"max_idle_conns", setPool)
+	config.OnChange(config.DBKeyPrefix(name)+"max_open_conns", setPool)
+	Lifecycle().Register(lifecycle.StageDB, label, func(ctx context.Context) error {
+		return gormx.Close(db)
 	})
 
-	return demoDB
+	return nil
 }
@@ -0,0 +1,56 @@
+// Package di 服务注入
+package di
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"go-demo/config"
+	"go-demo/pkg/diagx"
+	"go-demo/pkg/gox"
+	"go-demo/pkg/lifecycle"
+)
+
+var (
+	diag     *diagx.Diag
+	diagOnce sync.Once
+)
+
+// Diag 运行时诊断
+//
+//	各服务在此注册状态函数, 通过 ServeDiag() 启动的诊断服务输出.
+func Diag() *diagx.Diag {
+	diagOnce.Do(func() {
+		diag = diagx.New(diagx.NewDiagReq{
+			AllowIPs: config.GetStringSlice("diag_allow_ips"),
+			Config: func() any {
+				return config.All()
+			},
+		})
+	})
+
+	return diag
+}
+
+// ServeDiag 启动诊断服务
+//
+//	port 为 0 表示不启动. 诊断服务使用独立端口, 不要对外暴露.
+//	来源 IP 在 diag_allow_ips 内, 或者通过 authorize 鉴权时允许访问, 比如 service.Auth.AdminRequest 校验管理员登录.
+func ServeDiag(port int, authorize diagx.AuthorizeFunc) {
+	if port == 0 {
+		return
+	}
+
+	diagServer := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: Diag().Handler(authorize),
+	}
+	Lifecycle().Register(lifecycle.StageServer, "diag", diagServer.Shutdown)
+	gox.SafeGo(func() {
+		if err := diagServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			Logger().Error(err.Error())
+		}
+	})
+}
@@ -0,0 +1,26 @@
+// Package di 服务注入
+package di
+
+import (
+	"sync"
+	"time"
+
+	"go-demo/pkg/healthx"
+)
+
+var (
+	health     *healthx.Health
+	healthOnce sync.Once
+)
+
+// Health 健康检查
+//
+//	各服务创建时在此注册依赖检查, 入口通过 /healthz 与 /readyz 暴露检查结果.
+//	只检查进程已创建的服务, 未使用的服务不影响就绪状态.
+func Health() *healthx.Health {
+	healthOnce.Do(func() {
+		health = healthx.New(3 * time.Second)
+	})
+
+	return health
+}
@@ -0,0 +1,66 @@
+// Package di 服务注入
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-demo/config"
+	"go-demo/pkg/lifecycle"
+)
+
+var (
+	lc     *lifecycle.Lifecycle
+	lcOnce sync.Once
+)
+
+func init() {
+	// 关闭开始后就绪检查失败, 负载均衡不再分配新的请求
+	Health().Register("lifecycle", func(ctx context.Context) error {
+		if Lifecycle().IsStopping() {
+			return errors.New("正在关闭")
+		}
+		return nil
+	})
+}
+
+// Lifecycle 生命周期管理
+//
+//	各服务创建时在此注册关闭函数, 入口退出时调用 Shutdown() 按阶段关闭: 停止接收 -> Goroutine 池 -> DB -> Redis -> 消息队列 client -> 刷新缓冲.
+func Lifecycle() *lifecycle.Lifecycle {
+	lcOnce.Do(func() {
+		lc = lifecycle.New()
+	})
+
+	return lc
+}
+
+// ShutdownTimeout 优雅停止超时时间
+func ShutdownTimeout() time.Duration {
+	return config.GetDuration("shutdown_timeout")
+}
+
+// BeginShutdown 收到退出信号时调用, 标记开始关闭并等待 shutdown_delay
+//
+//	就绪检查立即失败, 等待负载均衡摘除实例后再停止接收请求, 等待期间请求正常处理. 之后调用 Shutdown.
+func BeginShutdown() {
+	Lifecycle().MarkStopping()
+	if delay := config.GetDuration("shutdown_delay"); delay > 0 {
+		Logger().Info(fmt.Sprintf("就绪检查已失败, %s 后停止接收请求", delay))
+		time.Sleep(delay)
+	}
+}
+
+// Shutdown 关闭所有已创建的服务
+//
+//	超时时间为 shutdown_timeout 配置.
+func Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout())
+	defer cancel()
+	if err := Lifecycle().Shutdown(ctx); err != nil {
+		Logger().Error(err.Error())
+	}
+}
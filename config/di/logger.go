@@ -2,28 +2,62 @@
 package di
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"go-demo/config"
+	"go-demo/pkg/gox"
+	"go-demo/pkg/lifecycle"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-var zapLogger *zap.Logger
+var (
+	zapLogger *zap.Logger
+	zapLevel  = zap.NewAtomicLevel() // 日志级别, 运行时可修改
+)
+
+// 日志级别名称, 与 error_log_level 配置一致
+var logLevels = map[string]zapcore.Level{
+	"Debug": zapcore.DebugLevel,
+	"Info":  zapcore.InfoLevel,
+	"Warn":  zapcore.WarnLevel,
+	"Error": zapcore.ErrorLevel,
+}
 
 func init() { // 日志服务最为基础, 日志初始化失败, 程序不允许启动
 	// 创建输出位置
 	syncers := make([]zapcore.WriteSyncer, 0) // NewMultiWriteSyncer() 可以添加多个 syncer, 逗号分隔
 	errorLog := config.GetString("error_log")
 	if errorLog != "" { // 输出到文件
+		// 先确认文件可写, lumberjack 在第一次写入时才会打开文件
 		logFile, err := os.OpenFile(errorLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o664)
 		if err != nil {
 			panic(err)
 		}
-		fileSyncer := zapcore.AddSync(logFile)
+		_ = logFile.Close()
+		// 文件切割
+		rotator := &lumberjack.Logger{
+			Filename:   errorLog,
+			MaxSize:    config.GetInt("error_log_max_size"),
+			MaxBackups: config.GetInt("error_log_max_backups"),
+			MaxAge:     config.GetInt("error_log_max_age"),
+			Compress:   config.GetBool("error_log_compress"),
+			LocalTime:  true,
+		}
+		if config.GetBool("error_log_rotate_daily") {
+			rotateDaily(rotator)
+		}
+		fileSyncer := zapcore.AddSync(rotator)
 		syncers = append(syncers, fileSyncer)
-	} else { // 输出到控制台
+	}
+	if errorLog == "" || config.GetBool("error_log_stdout") { // 输出到控制台
 		consoleSyncer := zapcore.AddSync(os.Stdout)
 		syncers = append(syncers, consoleSyncer)
 	}
@@ -33,25 +67,86 @@ func init() { // 日志服务最为基础, 日志初始化失败, 程序不允
 	encoder := zapcore.NewJSONEncoder(encoderConfig)
 
 	// 创建 Core
-	logLevel := zapcore.DebugLevel
-	switch config.GetString("error_log_level") {
-	case "Debug":
-		logLevel = zapcore.DebugLevel
-	case "Info":
-		logLevel = zapcore.InfoLevel
-	case "Warn":
-		logLevel = zapcore.WarnLevel
-	case "Error":
-		logLevel = zapcore.ErrorLevel
+	if err := SetLogLevel(config.GetString("error_log_level")); err != nil {
+		zapLevel.SetLevel(zapcore.DebugLevel)
 	}
-	zapCore := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(syncers...), logLevel) // 允许记录所有级别日志
+	zapCore := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(syncers...), zapLevel)
 	// 创建 Logger
 	zapLogger = zap.New(zapCore, zap.AddStacktrace(zapcore.ErrorLevel)) // 错误日志记录栈信息
 	// 替换 zap 包中全局的 zapLogger 实例, 后续在其他包中只需使用 zap.L() 调用即可
 	zap.ReplaceGlobals(zapLogger)
+	// 退出时刷新缓冲, 最先注册, 同阶段中最后执行
+	Lifecycle().Register(lifecycle.StageFlush, "logger", func(ctx context.Context) error {
+		_ = zapLogger.Sync() // 控制台输出 Sync 会报 invalid argument, 忽略
+		return nil
+	})
+
+	// 配置热更新
+	config.OnChange("error_log_level", func() {
+		if err := SetLogLevel(config.GetString("error_log_level")); err != nil {
+			zapLogger.Error(err.Error())
+		}
+	})
+
+	// SIGUSR1 在 Debug 与配置级别之间切换, 用于线上临时排查问题
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1)
+	gox.SafeGo(func() {
+		for range sigCh {
+			level := "Debug"
+			if LogLevel() == "Debug" {
+				level = config.GetString("error_log_level")
+			}
+			if err := SetLogLevel(level); err != nil {
+				zapLogger.Error(err.Error())
+				continue
+			}
+			zapLogger.Warn("日志级别已切换为 " + LogLevel())
+		}
+	})
+}
+
+// rotateDaily 每天零点切割日志文件
+func rotateDaily(rotator *lumberjack.Logger) {
+	gox.SafeGo(func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+			time.Sleep(next.Sub(now))
+			if err := rotator.Rotate(); err != nil {
+				zap.L().Error(err.Error())
+			}
+		}
+	})
 }
 
 // Logger 日志
 func Logger() *zap.Logger {
 	return zapLogger
 }
+
+// LogLevel 当前日志级别
+//
+//	Debug, Info, Warn, Error.
+func LogLevel() string {
+	for name, level := range logLevels {
+		if level == zapLevel.Level() {
+			return name
+		}
+	}
+
+	return zapLevel.Level().String()
+}
+
+// SetLogLevel 运行时修改日志级别
+//
+//	level 为 Debug, Info, Warn, Error. 仅对当前进程生效, 重启或 error_log_level 配置热更新后恢复为配置级别.
+func SetLogLevel(level string) error {
+	zapLevelValue, ok := logLevels[level]
+	if !ok {
+		return errors.New("未知日志级别: " + level)
+	}
+	zapLevel.SetLevel(zapLevelValue)
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package di
+
+import (
+	"context"
+	"sync"
+
+	"go-demo/config"
+	"go-demo/pkg/gox"
+	"go-demo/pkg/lifecycle"
+	"go-demo/pkg/outbox"
+)
+
+var outboxRelayOnce sync.Once
+
+// StartOutboxRelay 启动事务发件箱投递, 将 demo 库 t_outbox 中已提交的任务发送到消息队列
+//
+//	demo-queue 启动时调用, 轮询间隔与保留时间见配置 outbox_poll_interval, outbox_retention. 关闭时等待当前批次完成.
+func StartOutboxRelay() {
+	outboxRelayOnce.Do(func() {
+		relay := outbox.NewRelay(DemoDB(), QueueClient(), outbox.RelayConfig{
+			Interval:  config.GetDuration("outbox_poll_interval"),
+			Retention: config.GetDuration("outbox_retention"),
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		gox.SafeGo(func() {
+			defer close(done)
+			relay.Run(ctx)
+		})
+		Lifecycle().Register(lifecycle.StageServer, "outbox:relay", func(ctx context.Context) error {
+			cancel()
+			return lifecycle.Wait(ctx, func() { <-done })
+		})
+	})
+}
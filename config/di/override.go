@@ -0,0 +1,69 @@
+package di
+
+import (
+	"sync"
+
+	"go-demo/pkg/queuex"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Overrides 测试时替换的服务, 为 nil 的不替换
+type Overrides struct {
+	CacheRedis   *redis.Client
+	StorageRedis *redis.Client
+	JWTRedis     *redis.Client
+	QueueClient  queuex.Client
+	DemoDB       *gorm.DB
+}
+
+var (
+	overrides   Overrides
+	overridesMu sync.RWMutex
+)
+
+// Override 替换服务, 用于测试
+//
+//	替换后对应的函数返回替换的服务, 不再按配置连接, 比如 miniredis 的 client, queuex.Recorder, SQLite 内存数据库.
+//	返回的函数恢复替换前的服务. 替换是全局的, 使用的测试不能并行执行. 不负责关闭替换的服务.
+func Override(o Overrides) (restore func()) {
+	overridesMu.Lock()
+	previous := overrides
+	if o.CacheRedis != nil {
+		overrides.CacheRedis = o.CacheRedis
+	}
+	if o.StorageRedis != nil {
+		overrides.StorageRedis = o.StorageRedis
+	}
+	if o.JWTRedis != nil {
+		overrides.JWTRedis = o.JWTRedis
+	}
+	if o.QueueClient != nil {
+		overrides.QueueClient = o.QueueClient
+	}
+	overridesMu.Unlock()
+
+	var previousDB *gorm.DB
+	if o.DemoDB != nil {
+		dbsMu.RLock()
+		previousDB = dbs["demo"]
+		dbsMu.RUnlock()
+		SetDB("demo", o.DemoDB)
+	}
+
+	return func() {
+		overridesMu.Lock()
+		overrides = previous
+		overridesMu.Unlock()
+		if o.DemoDB != nil {
+			SetDB("demo", previousDB)
+		}
+	}
+}
+
+func overridden() Overrides {
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	return overrides
+}
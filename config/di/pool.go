@@ -2,36 +2,132 @@
 package di
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"go-demo/config"
+	"go-demo/pkg/gox"
+	"go-demo/pkg/lifecycle"
 
 	"github.com/alitto/pond"
 	"go.uber.org/zap"
 )
 
 var (
-	workerPool *pond.WorkerPool
-	wpOnce     sync.Once
+	workerPool   atomic.Pointer[pond.WorkerPool]
+	workerPoolMu sync.Mutex
+	wpOnce       sync.Once
 )
 
 // Pool 公共 Goroutine 池
+//
+//	worker_pool 配置热更新时创建新池替换, 旧池延迟停止, 等待已提交的任务完成. 不要长期持有返回值, 每次使用时调用.
 func Pool() *pond.WorkerPool {
 	wpOnce.Do(func() {
-		workerPool = pond.New(config.GetInt("worker_pool"), 0, pond.PanicHandler(func(a any) {
-			zap.L().Error(fmt.Sprint(a))
-		}))
+		workerPool.Store(newPool(config.GetInt("worker_pool")))
+		config.OnChange("worker_pool", func() {
+			workerPoolMu.Lock()
+			defer workerPoolMu.Unlock()
+			oldPool := workerPool.Swap(newPool(config.GetInt("worker_pool")))
+			// 替换前取得旧池的调用方可能还未提交任务, 延迟停止; 退出时与独享池一起等待
+			trackPool(oldPool)
+			gox.SafeGo(func() {
+				time.Sleep(poolRetireDelay)
+				oldPool.StopAndWait()
+			})
+		})
+		Diag().RegisterStats("pool", func() any {
+			return poolStats(workerPool.Load())
+		})
+		Lifecycle().Register(lifecycle.StagePool, "pool", func(ctx context.Context) error {
+			workerPoolMu.Lock()
+			defer workerPoolMu.Unlock()
+			return lifecycle.Wait(ctx, workerPool.Load().StopAndWait)
+		})
 	})
 
-	return workerPool
+	return workerPool.Load()
 }
 
+// 热更新替换后, 旧的公共 Goroutine 池延迟停止的时间
+const poolRetireDelay = 10 * time.Second
+
+func newPool(maxWorkers int) *pond.WorkerPool {
+	return pond.New(maxWorkers, 0, pond.PanicHandler(func(a any) {
+		zap.L().Error(fmt.Sprint(a))
+	}))
+}
+
+// 未停止的独享 Goroutine 池与热更新替换下的公共池, 退出时统一等待
+var (
+	separatePools     = map[*pond.WorkerPool]struct{}{}
+	separatePoolsMu   sync.Mutex
+	separatePoolsOnce sync.Once
+)
+
 // PoolSeparate 独享 Goroutine 池
 //
 //	一次请求提交大量数据, 使用独享 Goroutine 池起限流作用.
+//	用完需要调用 StopAndWait() 停止, 程序退出时会等待未停止的池中任务完成.
 func PoolSeparate(maxWorkers int) *pond.WorkerPool {
-	return pond.New(maxWorkers, 0, pond.PanicHandler(func(a any) {
-		zap.L().Error(fmt.Sprint(a))
-	}))
+	pool := newPool(maxWorkers)
+	trackPool(pool)
+
+	return pool
+}
+
+// trackPool 记录未停止的池, 程序退出时等待
+func trackPool(pool *pond.WorkerPool) {
+	separatePoolsOnce.Do(func() {
+		Diag().RegisterStats("pool:separate", func() any {
+			separatePoolsMu.Lock()
+			defer separatePoolsMu.Unlock()
+			stats := make([]map[string]any, 0, len(separatePools))
+			for pool := range separatePools {
+				if !pool.Stopped() {
+					stats = append(stats, poolStats(pool))
+				}
+			}
+			return stats
+		})
+		Lifecycle().Register(lifecycle.StagePool, "pool:separate", func(ctx context.Context) error {
+			separatePoolsMu.Lock()
+			pools := make([]*pond.WorkerPool, 0, len(separatePools))
+			for pool := range separatePools {
+				pools = append(pools, pool)
+			}
+			separatePoolsMu.Unlock()
+
+			return lifecycle.Wait(ctx, func() {
+				for _, pool := range pools {
+					pool.StopAndWait()
+				}
+			})
+		})
+	})
+
+	separatePoolsMu.Lock()
+	defer separatePoolsMu.Unlock()
+	for p := range separatePools { // 清理已停止的池
+		if p.Stopped() {
+			delete(separatePools, p)
+		}
+	}
+	separatePools[pool] = struct{}{}
+}
+
+// poolStats Goroutine 池状态
+func poolStats(pool *pond.WorkerPool) map[string]any {
+	return map[string]any{
+		"max_workers":     pool.MaxWorkers(),
+		"running_workers": pool.RunningWorkers(),
+		"idle_workers":    pool.IdleWorkers(),
+		"submitted_tasks": pool.SubmittedTasks(),
+		"waiting_tasks":   pool.WaitingTasks(),
+		"completed_tasks": pool.CompletedTasks(),
+		"failed_tasks":    pool.FailedTasks(),
+	}
 }
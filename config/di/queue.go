@@ -2,30 +2,52 @@
 package di
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
 	"go-demo/config"
+	"go-demo/pkg/lifecycle"
+	"go-demo/pkg/queuex"
 
 	"github.com/hibiken/asynq"
 )
 
+// queueRedisOpt 消息队列使用的 redis, 库号为 redis_index_queue
+func queueRedisOpt() asynq.RedisClientOpt {
+	return asynq.RedisClientOpt{
+		Addr: fmt.Sprintf("%s:%d",
+			config.GetString("redis_host"),
+			config.GetInt("redis_port"),
+		),
+		DB:       config.GetInt("redis_index_queue"),
+		Password: config.GetString("redis_auth"),
+	}
+}
+
 /******************** 消息队列 client ********************/
 var (
 	queueClient     *asynq.Client
 	queueClientOnce sync.Once
 )
 
-// QueueClient 消息队列 client
-func QueueClient() *asynq.Client {
+// QueueClient 消息队列 client, 用于 queuex 发送任务
+func QueueClient() queuex.Client {
+	if client := overridden().QueueClient; client != nil {
+		return client
+	}
+
+	return asynqClient()
+}
+
+func asynqClient() *asynq.Client {
 	queueClientOnce.Do(func() {
-		queueClient = asynq.NewClient(asynq.RedisClientOpt{
-			Addr: fmt.Sprintf("%s:%d",
-				config.GetString("redis_host"),
-				config.GetInt("redis_port"),
-			),
-			DB:       config.GetInt("redis_index_queue"),
-			Password: config.GetString("redis_auth"),
+		queueClient = asynq.NewClient(queueRedisOpt())
+		Health().Register("asynq", func(ctx context.Context) error {
+			return queueClient.Ping()
+		})
+		Lifecycle().Register(lifecycle.StageQueue, "asynq:client", func(ctx context.Context) error {
+			return queueClient.Close()
 		})
 	})
 
@@ -42,26 +64,42 @@ var (
 func QueueServer() *asynq.Server {
 	queueServerOnce.Do(func() {
 		queueServer = asynq.NewServer(
-			asynq.RedisClientOpt{
-				Addr: fmt.Sprintf("%s:%d",
-					config.GetString("redis_host"),
-					config.GetInt("redis_port"),
-				),
-				DB:       config.GetInt("redis_index_queue"),
-				Password: config.GetString("redis_auth"),
-			},
+			queueRedisOpt(),
 			asynq.Config{
-				// Specify how many concurrent workers to use
-				Concurrency: 100,
-				// Optionally specify multiple queues with different priority.
-				Queues: map[string]int{
-					"default": 9,
-					"low":     1,
-				},
+				// Worker 并发数
+				Concurrency: config.GetInt("queue_concurrency"),
+				// 队列及权重, 按权重比例拉取任务, 见配置 queues
+				Queues: config.GetStringMapInt("queues"),
+				// 优雅停止时等待处理中任务完成的时间, 超时的任务会重新入队
+				ShutdownTimeout: ShutdownTimeout(),
 				// See the godoc for other configuration options
 			},
 		)
+		Health().Register("asynq", func(ctx context.Context) error {
+			return queueServer.Ping()
+		})
+		Lifecycle().Register(lifecycle.StageServer, "asynq:server", func(ctx context.Context) error {
+			return lifecycle.Wait(ctx, queueServer.Shutdown)
+		})
 	})
 
 	return queueServer
 }
+
+/******************** 消息队列 inspector ********************/
+var (
+	queueInspector     *asynq.Inspector
+	queueInspectorOnce sync.Once
+)
+
+// QueueInspector 消息队列 inspector, 用于查看与管理队列和任务, 见 demo-cli queue
+func QueueInspector() *asynq.Inspector {
+	queueInspectorOnce.Do(func() {
+		queueInspector = asynq.NewInspector(queueRedisOpt())
+		Lifecycle().Register(lifecycle.StageQueue, "asynq:inspector", func(ctx context.Context) error {
+			return queueInspector.Close()
+		})
+	})
+
+	return queueInspector
+}
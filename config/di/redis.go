@@ -2,12 +2,16 @@
 package di
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
 	"go-demo/config"
+	"go-demo/pkg/lifecycle"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 )
 
 /******************** 缓存 redis ********************/
@@ -20,6 +24,9 @@ var (
 //
 //	删除缓存数据不会引发业务错误
 func CacheRedis() *redis.Client {
+	if rdb := overridden().CacheRedis; rdb != nil {
+		return rdb
+	}
 	cacheRedisOnce.Do(func() {
 		cacheRedis = redis.NewClient(&redis.Options{
 			Addr: fmt.Sprintf("%s:%d",
@@ -29,6 +36,18 @@ func CacheRedis() *redis.Client {
 			Password: config.GetString("redis_auth"),
 			DB:       config.GetInt("redis_index_cache"),
 		})
+		if err := redisotel.InstrumentTracing(cacheRedis); err != nil { // 链路追踪
+			zap.L().Error(err.Error())
+		}
+		Diag().RegisterStats("redis:cache", func() any {
+			return cacheRedis.PoolStats()
+		})
+		Health().Register("redis:cache", func(ctx context.Context) error {
+			return cacheRedis.Ping(ctx).Err()
+		})
+		Lifecycle().Register(lifecycle.StageRedis, "redis:cache", func(ctx context.Context) error {
+			return cacheRedis.Close()
+		})
 	})
 
 	return cacheRedis
@@ -44,6 +63,9 @@ var (
 //
 //	删除存储数据会引发业务错误
 func StorageRedis() *redis.Client {
+	if rdb := overridden().StorageRedis; rdb != nil {
+		return rdb
+	}
 	storageRedisOnce.Do(func() {
 		storageRedis = redis.NewClient(&redis.Options{
 			Addr: fmt.Sprintf("%s:%d",
@@ -53,6 +75,18 @@ func StorageRedis() *redis.Client {
 			Password: config.GetString("redis_auth"),
 			DB:       config.GetInt("redis_index_storage"),
 		})
+		if err := redisotel.InstrumentTracing(storageRedis); err != nil { // 链路追踪
+			zap.L().Error(err.Error())
+		}
+		Diag().RegisterStats("redis:storage", func() any {
+			return storageRedis.PoolStats()
+		})
+		Health().Register("redis:storage", func(ctx context.Context) error {
+			return storageRedis.Ping(ctx).Err()
+		})
+		Lifecycle().Register(lifecycle.StageRedis, "redis:storage", func(ctx context.Context) error {
+			return storageRedis.Close()
+		})
 	})
 
 	return storageRedis
@@ -66,6 +100,9 @@ var (
 
 // JWTRedis JWT redis 实例
 func JWTRedis() *redis.Client {
+	if rdb := overridden().JWTRedis; rdb != nil {
+		return rdb
+	}
 	jwtRedisOnce.Do(func() {
 		jwtRedis = redis.NewClient(&redis.Options{
 			Addr: fmt.Sprintf("%s:%d",
@@ -75,6 +112,18 @@ func JWTRedis() *redis.Client {
 			Password: config.GetString("redis_auth"),
 			DB:       config.GetInt("redis_index_jwt"),
 		})
+		if err := redisotel.InstrumentTracing(jwtRedis); err != nil { // 链路追踪
+			zap.L().Error(err.Error())
+		}
+		Diag().RegisterStats("redis:jwt", func() any {
+			return jwtRedis.PoolStats()
+		})
+		Health().Register("redis:jwt", func(ctx context.Context) error {
+			return jwtRedis.Ping(ctx).Err()
+		})
+		Lifecycle().Register(lifecycle.StageRedis, "redis:jwt", func(ctx context.Context) error {
+			return jwtRedis.Close()
+		})
 	})
 
 	return jwtRedis
@@ -0,0 +1,46 @@
+// Package di 服务注入
+package di
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"go-demo/config"
+	"go-demo/pkg/gox"
+	"go-demo/pkg/lifecycle"
+	"go-demo/pkg/otelx"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+var (
+	tracerProvider     *sdktrace.TracerProvider
+	tracerProviderOnce gox.Once
+)
+
+// TracerProvider 链路追踪
+//
+//	需要在入口启动时调用, 设置全局 TracerProvider 后 gin/GORM/go-redis/asynq 的埋点才会生效.
+//	服务名取可执行文件名, 如 demo-api. trace_exporter 为 off 时返回 nil.
+func TracerProvider() *sdktrace.TracerProvider {
+	_ = tracerProviderOnce.Do(func() (err error) {
+		tracerProvider, err = otelx.NewTracerProvider(otelx.NewTracerProviderReq{
+			ServiceName:  filepath.Base(os.Args[0]),
+			Exporter:     config.GetString("trace_exporter"),
+			OTLPEndpoint: config.GetString("trace_otlp_endpoint"),
+			OTLPInsecure: config.GetBool("trace_otlp_insecure"),
+			SampleRatio:  config.App.Trace.SamplePercent / 100, // 配置缺失时为 100, 0 表示不采样
+		})
+		if err != nil || tracerProvider == nil {
+			return
+		}
+		Lifecycle().Register(lifecycle.StageFlush, "tracer", func(ctx context.Context) error {
+			return tracerProvider.Shutdown(ctx)
+		})
+
+		return
+	})
+
+	return tracerProvider
+}
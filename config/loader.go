@@ -0,0 +1,118 @@
+// Package config 配置实现
+package config
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigDirEnv 外部配置目录的环境变量, 目录中的配置文件覆盖内置的同层配置, 修改后无需重新编译
+const ConfigDirEnv = "CONFIG_DIR"
+
+// 内置配置文件, 编译进程序
+//
+//go:embed files
+var embedFiles embed.FS
+
+// load 加载配置文件
+//
+//	配置文件命名为 <layer>_<type>.<ext>, 比如 testing_db.yml, common_app.toml, local.yml.
+//	layer 为 common, 运行环境或 local, 仅加载 common, runtimeEnv, local 三层, 按此顺序返回; ext 支持 yml, yaml, toml.
+//	每层先加载内置的 config/files/, 再加载外部目录 configDir, 同层同键名后加载的覆盖先加载的.
+func load(runtimeEnv, configDir string) ([]Layer, error) {
+	layerNames := []string{"common", runtimeEnv, "local"}
+	values := map[string]map[string]any{}
+
+	sources := []fs.FS{}
+	embedRoot, err := fs.Sub(embedFiles, "files")
+	if err != nil {
+		return nil, err
+	}
+	sources = append(sources, embedRoot)
+	if configDir != "" {
+		sources = append(sources, os.DirFS(configDir))
+	}
+
+	for _, source := range sources {
+		names, err := configFiles(source)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			ext := filepath.Ext(name)
+			layer, _, _ := strings.Cut(strings.TrimSuffix(name, ext), "_")
+			if !lo.Contains(layerNames, layer) {
+				continue
+			}
+
+			content, err := fs.ReadFile(source, name)
+			if err != nil {
+				return nil, err
+			}
+			fileValues, err := parse(content, ext)
+			if err != nil {
+				return nil, fmt.Errorf("配置文件 %s 解析失败: %w", name, err)
+			}
+			if _, ok := values[layer]; !ok {
+				values[layer] = map[string]any{}
+			}
+			for k, v := range fileValues {
+				values[layer][k] = v
+			}
+		}
+	}
+
+	layers := make([]Layer, 0, len(layerNames))
+	for _, name := range layerNames {
+		if layerValues, ok := values[name]; ok {
+			layers = append(layers, Layer{Name: name, Values: layerValues})
+		}
+	}
+
+	return layers, nil
+}
+
+// configFiles 目录中的配置文件, 按文件名排序
+func configFiles(source fs.FS) ([]string, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml" && ext != ".toml") {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// parse 解析配置文件内容
+func parse(content []byte, ext string) (map[string]any, error) {
+	values := map[string]any{}
+	switch ext {
+	case ".toml":
+		if err := toml.Unmarshal(content, &values); err != nil {
+			return nil, err
+		}
+	default:
+		if err := yaml.Unmarshal(content, &values); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,155 @@
+// Package config 配置实现
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Layer 配置层
+type Layer struct {
+	Name   string
+	Values map[string]any
+}
+
+// Provider 配置源
+//
+//	Load 加载配置, 返回的配置层优先级从低到高.
+//	Watch 监听配置源, 变化时调用 onChange, ctx 取消后返回. 不支持监听的配置源直接返回.
+type Provider interface {
+	Load() ([]Layer, error)
+	Watch(ctx context.Context, onChange func())
+}
+
+// FileProvider 配置文件配置源
+//
+//	内置配置文件编译进程序, 不会变化; 外部目录 Dir 按 Interval 轮询文件修改时间与大小, 有变化即重新加载.
+type FileProvider struct {
+	RuntimeEnv string
+	Dir        string
+	Interval   time.Duration // 轮询间隔, 0 不监听
+}
+
+// NewFileProvider 创建配置文件配置源, 外部目录为环境变量 CONFIG_DIR
+func NewFileProvider(interval time.Duration) *FileProvider {
+	return &FileProvider{
+		RuntimeEnv: RuntimeEnv(),
+		Dir:        os.Getenv(ConfigDirEnv),
+		Interval:   interval,
+	}
+}
+
+func (p *FileProvider) Load() ([]Layer, error) {
+	return load(p.RuntimeEnv, p.Dir)
+}
+
+func (p *FileProvider) Watch(ctx context.Context, onChange func()) {
+	if p.Dir == "" || p.Interval <= 0 {
+		return
+	}
+	lastSignature := p.signature()
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if signature := p.signature(); signature != lastSignature {
+				lastSignature = signature
+				onChange()
+			}
+		}
+	}
+}
+
+// signature 外部目录中配置文件的名称, 修改时间与大小
+func (p *FileProvider) signature() string {
+	dir := os.DirFS(p.Dir)
+	names, err := configFiles(dir)
+	if err != nil {
+		zap.L().Error(err.Error())
+		return ""
+	}
+	signature := strings.Builder{}
+	for _, name := range names {
+		info, err := fs.Stat(dir, name)
+		if err != nil {
+			continue
+		}
+		signature.WriteString(fmt.Sprintf("%s:%d:%d;", name, info.ModTime().UnixNano(), info.Size()))
+	}
+
+	return signature.String()
+}
+
+// KVProvider 键值存储配置源, 比如 Redis Hash, etcd, Consul
+//
+//	Fetch 返回全部键值, 值按 YAML 解析, 比如 "30" 为整数, "[a, b]" 为切片. 按 Interval 轮询, 有变化即重新加载.
+type KVProvider struct {
+	Name     string // 配置层名
+	Fetch    func(ctx context.Context) (map[string]string, error)
+	Interval time.Duration // 轮询间隔, 0 不监听
+	Timeout  time.Duration // 单次读取超时, 默认 3 秒
+}
+
+func (p *KVProvider) Load() ([]Layer, error) {
+	kv, err := p.fetch()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]any, len(kv))
+	for k, v := range kv {
+		var value any
+		if err := yaml.Unmarshal([]byte(v), &value); err != nil {
+			value = v
+		}
+		values[k] = value
+	}
+
+	return []Layer{{Name: p.Name, Values: values}}, nil
+}
+
+func (p *KVProvider) Watch(ctx context.Context, onChange func()) {
+	if p.Interval <= 0 {
+		return
+	}
+	last, _ := p.fetch()
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			kv, err := p.fetch()
+			if err != nil {
+				zap.L().Error(err.Error())
+				continue
+			}
+			if !reflect.DeepEqual(kv, last) {
+				last = kv
+				onChange()
+			}
+		}
+	}
+}
+
+func (p *KVProvider) fetch() (map[string]string, error) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return p.Fetch(ctx)
+}
@@ -0,0 +1,233 @@
+// Package config 配置实现
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-demo/pkg/gox"
+
+	"go.uber.org/zap"
+)
+
+var (
+	providers   []Provider
+	reloadMu    sync.Mutex // 保证同一时间只有一次加载
+	subscribers = map[string][]func(){}
+	subMu       sync.RWMutex
+	watchOnce   sync.Once
+	watchCtx    context.Context // Watch 之后添加的配置源也会被监听
+)
+
+// ReloadResult 热更新结果
+type ReloadResult struct {
+	Changed         []string // 值有变化的配置项
+	RestartRequired []string // 值有变化但不支持热更新的配置项, 需要重启生效
+}
+
+// AddProvider 添加配置源
+//
+//	后添加的配置源优先级高, 环境变量仍然优先级最高. 添加后立即重新加载配置, 配置源加载失败时不添加.
+func AddProvider(p Provider) (ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	result, err := reload(append(providers[:len(providers):len(providers)], p))
+	if err != nil {
+		return result, err
+	}
+	providers = append(providers, p)
+	if watchCtx != nil {
+		watch(watchCtx, p)
+	}
+	notify(result.Changed)
+
+	return result, nil
+}
+
+// Reload 重新加载全部配置源
+//
+//	全部加载成功且通过 AppConfig 校验后整体替换, 否则保留原配置. 值有变化的配置项会通知 OnChange 订阅者;
+//	AppConfig 中未标记 hot:"true" 的配置项不支持热更新, 修改后会记录警告日志, 需要重启生效.
+func Reload() (ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	result, err := reload(providers)
+	if err != nil {
+		return result, err
+	}
+	notify(result.Changed)
+
+	return result, nil
+}
+
+func reload(providers []Provider) (ReloadResult, error) {
+	result := ReloadResult{Changed: []string{}, RestartRequired: []string{}}
+	layers, err := loadLayers(providers)
+	if err != nil {
+		return result, err
+	}
+
+	// 校验新配置
+	if err := bind(&AppConfig{}, "", func(key string) (any, error) {
+		return resolveSecret(layers, getFrom(layers, key), false)
+	}); err != nil {
+		return result, err
+	}
+
+	oldAll, newAll := All(), allFrom(layers)
+	for key := range mergeKeys(oldAll, newAll) {
+		if reflect.DeepEqual(oldAll[key], newAll[key]) {
+			continue
+		}
+		result.Changed = append(result.Changed, key)
+		if !Hot(key) {
+			result.RestartRequired = append(result.RestartRequired, key)
+		}
+	}
+	sort.Strings(result.Changed)
+	sort.Strings(result.RestartRequired)
+
+	configure.Store(&layers)
+	clearSecretCache()
+	if len(result.Changed) > 0 {
+		zap.L().Info("配置已更新: " + strings.Join(result.Changed, ", "))
+	}
+	if len(result.RestartRequired) > 0 {
+		zap.L().Warn("配置不支持热更新, 需要重启生效: " + strings.Join(result.RestartRequired, ", "))
+	}
+
+	return result, nil
+}
+
+// loadLayers 加载全部配置源, 最低优先级为 AppConfig 中声明的默认值
+func loadLayers(providers []Provider) ([]Layer, error) {
+	defaultValues := map[string]any{}
+	walkTags(reflect.TypeOf(AppConfig{}), func(key string, tag reflect.StructTag) {
+		if defaultValue, ok := tag.Lookup("default"); ok {
+			defaultValues[key] = defaultValue
+		}
+	})
+	layers := []Layer{{Name: "default", Values: defaultValues}}
+
+	for _, p := range providers {
+		providerLayers, err := p.Load()
+		if err != nil {
+			return nil, fmt.Errorf("配置源 %T 加载失败: %w", p, err)
+		}
+		layers = append(layers, providerLayers...)
+	}
+
+	return layers, nil
+}
+
+func mergeKeys(maps ...map[string]any) map[string]struct{} {
+	keys := map[string]struct{}{}
+	for _, m := range maps {
+		for k := range m {
+			keys[k] = struct{}{}
+		}
+	}
+
+	return keys
+}
+
+// 支持热更新的配置项, AppConfig, DBConfig 中标记了 hot:"true"
+var (
+	hotKeys   = hotTags(AppConfig{})
+	dbHotKeys = hotTags(DBConfig{})
+)
+
+func hotTags(v any) map[string]bool {
+	keys := map[string]bool{}
+	walkTags(reflect.TypeOf(v), func(key string, tag reflect.StructTag) {
+		if tag.Get("hot") == "true" {
+			keys[key] = true
+		}
+	})
+	return keys
+}
+
+// Hot 配置项是否支持热更新
+func Hot(key string) bool {
+	if hotKeys[key] {
+		return true
+	}
+	// 按名称声明的数据库配置
+	if name, ok := strings.CutPrefix(key, dbKeyPrefix); ok {
+		for dbKey := range dbHotKeys {
+			if strings.HasSuffix(name, "_"+dbKey) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// OnChange 订阅配置项变化
+//
+//	热更新后配置项的值有变化时同步调用 f, f 中使用 GetInt() 等函数获取新值. 仅对 AppConfig 中标记了 hot:"true" 的配置项有意义.
+func OnChange(key string, f func()) {
+	if !Hot(key) {
+		zap.L().Warn("配置 " + key + " 不支持热更新, 订阅不会生效")
+	}
+	subMu.Lock()
+	defer subMu.Unlock()
+	subscribers[key] = append(subscribers[key], f)
+}
+
+func notify(changed []string) {
+	subMu.RLock()
+	fs := make([]func(), 0)
+	for _, key := range changed {
+		if Hot(key) {
+			fs = append(fs, subscribers[key]...)
+		}
+	}
+	subMu.RUnlock()
+
+	for _, f := range fs {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					zap.L().Error(fmt.Sprint(r))
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+// Watch 监听全部配置源, 有变化时热更新
+//
+//	fileInterval 为外部配置目录 CONFIG_DIR 的轮询间隔, 0 不监听. ctx 取消后停止监听, 多次调用只有第一次生效.
+func Watch(ctx context.Context, fileInterval time.Duration) {
+	watchOnce.Do(func() {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+		watchCtx = ctx
+		for _, p := range providers {
+			if fileProvider, ok := p.(*FileProvider); ok {
+				fileProvider.Interval = fileInterval
+			}
+			watch(ctx, p)
+		}
+	})
+}
+
+func watch(ctx context.Context, p Provider) {
+	gox.SafeGo(func() {
+		p.Watch(ctx, func() {
+			if _, err := Reload(); err != nil {
+				zap.L().Error("配置热更新失败, 保留原配置: " + err.Error())
+			}
+		})
+	})
+}
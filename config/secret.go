@@ -0,0 +1,121 @@
+// Package config 配置实现
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"go-demo/pkg/secretx"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	// SecretScheme 引用密钥的配置值前缀, 比如 jwt_secret: secret://jwt_secret
+	SecretScheme = "secret://"
+	// MasterKeyEnv 加密密钥主密钥的环境变量, base64 编码的 32 字节
+	MasterKeyEnv = "CONFIG_MASTER_KEY"
+	// SecretsDirEnv 文件密钥目录的环境变量, 默认为 /run/secrets
+	SecretsDirEnv = "SECRETS_DIR"
+	// encryptedSecretsKey 加密密钥的配置项, 密钥名称到 demo-cli config encrypt 生成的密文的映射
+	encryptedSecretsKey = "encrypted_secrets"
+)
+
+var (
+	secretProviders   []secretx.Provider // AddSecretProvider() 添加的密钥提供者
+	secretProvidersMu sync.RWMutex
+
+	secretCache   = map[string]string{} // 当前配置中已解析的密钥, 热更新后清空
+	secretCacheMu sync.RWMutex
+)
+
+// AddSecretProvider 添加密钥提供者, 比如 Vault, KMS
+//
+//	查找顺序: 环境变量 SECRET_<NAME> > 文件 SECRETS_DIR/<name> > 添加的提供者 > 加密密钥 encrypted_secrets.
+func AddSecretProvider(p secretx.Provider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders = append(secretProviders, p)
+	clearSecretCache()
+}
+
+// resolveSecret 解析配置值中的密钥引用, 非密钥引用原样返回
+func resolveSecret(layers []Layer, value any, useCache bool) (any, error) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, SecretScheme) {
+		return value, nil
+	}
+	name := strings.TrimPrefix(s, SecretScheme)
+
+	if useCache {
+		secretCacheMu.RLock()
+		secret, ok := secretCache[name]
+		secretCacheMu.RUnlock()
+		if ok {
+			return secret, nil
+		}
+	}
+
+	provider, err := secretProvider(layers)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := provider.Get(name)
+	if errors.Is(err, secretx.ErrNotFound) {
+		if os.Getenv(MasterKeyEnv) == "" {
+			return nil, fmt.Errorf("密钥 %s 不存在, 加密密钥需要设置环境变量 %s", name, MasterKeyEnv)
+		}
+		return nil, fmt.Errorf("密钥 %s 不存在", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("密钥 %s 解析失败: %w", name, err)
+	}
+
+	if useCache {
+		secretCacheMu.Lock()
+		secretCache[name] = secret
+		secretCacheMu.Unlock()
+	}
+
+	return secret, nil
+}
+
+func secretProvider(layers []Layer) (provider secretx.Provider, err error) {
+	secretsDir, ok := os.LookupEnv(SecretsDirEnv)
+	if !ok {
+		secretsDir = "/run/secrets"
+	}
+	providers := []secretx.Provider{
+		secretx.EnvProvider{Prefix: "SECRET_"},
+		secretx.FileProvider{Dir: secretsDir},
+	}
+
+	secretProvidersMu.RLock()
+	providers = append(providers, secretProviders...)
+	secretProvidersMu.RUnlock()
+
+	ciphertexts := map[string]string{}
+	if value := getFrom(layers, encryptedSecretsKey); value != nil {
+		if ciphertexts, err = cast.ToStringMapStringE(value); err != nil {
+			return nil, fmt.Errorf("%s 配置格式错误: %w", encryptedSecretsKey, err)
+		}
+	}
+	if masterKey := os.Getenv(MasterKeyEnv); masterKey != "" && len(ciphertexts) > 0 {
+		aesProvider, err := secretx.NewAESGCMProvider(masterKey, ciphertexts)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, aesProvider)
+	}
+
+	return secretx.Chain(providers...), nil
+}
+
+func clearSecretCache() {
+	secretCacheMu.Lock()
+	defer secretCacheMu.Unlock()
+	secretCache = map[string]string{}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-demo/pkg/secretx"
+)
+
+func TestResolveSecret(t *testing.T) {
+	masterKey, err := secretx.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := secretx.Encrypt(masterKey, "encrypted_name", "from-aes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file_name"), []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "both_name"), []byte("from-file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(SecretsDirEnv, dir)
+	t.Setenv("SECRET_ENV_NAME", "from-env")
+	t.Setenv("SECRET_BOTH_NAME", "from-env")
+	t.Setenv(MasterKeyEnv, masterKey)
+	layers := []Layer{{Name: "test", Values: map[string]any{
+		encryptedSecretsKey: map[string]any{"encrypted_name": ciphertext, "wrong_name": strings.Replace(ciphertext, "v1:", "v1:A", 1)},
+	}}}
+
+	tests := []struct {
+		name    string
+		value   any
+		want    any
+		wantErr string
+	}{
+		{name: "非密钥引用", value: "plain", want: "plain"},
+		{name: "非字符串", value: 3306, want: 3306},
+		{name: "环境变量", value: "secret://env_name", want: "from-env"},
+		{name: "文件, 去除末尾换行", value: "secret://file_name", want: "from-file"},
+		{name: "环境变量优先于文件", value: "secret://both_name", want: "from-env"},
+		{name: "加密密钥", value: "secret://encrypted_name", want: "from-aes"},
+		{name: "不存在", value: "secret://missing_name", wantErr: "密钥 missing_name 不存在"},
+		{name: "密文错误", value: "secret://wrong_name", wantErr: "密钥 wrong_name 解析失败"},
+		{name: "不允许目录外的文件", value: "secret://../file_name", wantErr: "不存在"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSecret(layers, tt.value, false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSecretCache(t *testing.T) {
+	t.Cleanup(clearSecretCache)
+	t.Setenv("SECRET_CACHED_NAME", "v1")
+	if got, err := resolveSecret(nil, "secret://cached_name", true); err != nil || got != "v1" {
+		t.Fatalf("got %v, %v", got, err)
+	}
+
+	// 热更新前使用缓存, 清空后重新解析
+	t.Setenv("SECRET_CACHED_NAME", "v2")
+	if got, _ := resolveSecret(nil, "secret://cached_name", true); got != "v1" {
+		t.Errorf("got %v, want 缓存的 v1", got)
+	}
+	clearSecretCache()
+	if got, _ := resolveSecret(nil, "secret://cached_name", true); got != "v2" {
+		t.Errorf("got %v, want v2", got)
+	}
+}
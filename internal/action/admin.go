@@ -0,0 +1,36 @@
+// Package action 命令行 action
+package action
+
+import (
+	"fmt"
+
+	"go-demo/internal/consts"
+	"go-demo/internal/service"
+
+	"github.com/urfave/cli/v2"
+)
+
+// 管理员相关命令行
+type admin struct{}
+
+var Admin admin
+
+// Token 签发管理员 JWT
+//
+//	没有管理员账户体系, 由能登录服务器并读取配置的运维人员签发, 用于访问 /admin/v1 管理接口. 同样写入 Redis 白名单, 有效期 30 天.
+func (admin) Token(c *cli.Context) error {
+	id := c.Int64("id")
+	if id <= 0 {
+		fmt.Println("请输入管理员 ID")
+		return nil
+	}
+
+	token, err := service.Auth.JWTLogin(consts.AdminJWT, id, c.String("name"))
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	fmt.Println("请求头携带 Authorization: Bearer <token> 访问 /admin/v1 管理接口")
+
+	return nil
+}
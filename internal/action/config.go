@@ -0,0 +1,70 @@
+// Package action 命令行 action
+package action
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"go-demo/config"
+	"go-demo/pkg/secretx"
+
+	"github.com/urfave/cli/v2"
+)
+
+// 配置相关命令行
+type configAction struct{}
+
+var Config configAction
+
+// GenKey 生成主密钥
+func (configAction) GenKey(c *cli.Context) error {
+	masterKey, err := secretx.GenerateKey()
+	if err != nil {
+		return err
+	}
+	fmt.Println(masterKey)
+	fmt.Printf("妥善保管, 运行时通过环境变量 %s 提供, 不要写入配置文件\n", config.MasterKeyEnv)
+
+	return nil
+}
+
+// Encrypt 加密密钥
+//
+//	demo-cli config encrypt <name> [value], 未传 value 时从标准输入读取, 避免明文留在 shell 历史中.
+//	主密钥为环境变量 CONFIG_MASTER_KEY, 输出的密文写入配置 encrypted_secrets, 配置项引用 secret://<name>.
+func (configAction) Encrypt(c *cli.Context) error {
+	name := c.Args().Get(0)
+	if name == "" {
+		fmt.Println("请输入密钥名称")
+		return nil
+	}
+	masterKey := os.Getenv(config.MasterKeyEnv)
+	if masterKey == "" {
+		fmt.Printf("请设置环境变量 %s, 可以使用 demo-cli config gen-key 生成\n", config.MasterKeyEnv)
+		return nil
+	}
+
+	value := c.Args().Get(1)
+	if c.Args().Len() < 2 {
+		fmt.Print("请输入密钥明文: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+
+	ciphertext, err := secretx.Encrypt(masterKey, name, value)
+	if err != nil {
+		return err
+	}
+	fmt.Println("将以下内容写入配置文件:")
+	fmt.Println()
+	fmt.Printf("%s: %s%s\n", name, config.SecretScheme, name)
+	fmt.Println("encrypted_secrets:")
+	fmt.Printf("  %s: %s\n", name, ciphertext)
+
+	return nil
+}
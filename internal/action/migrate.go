@@ -0,0 +1,127 @@
+// Package action 命令行 action
+package action
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"go-demo/config/di"
+	"go-demo/internal/migration"
+	"go-demo/pkg/migratex"
+
+	"github.com/urfave/cli/v2"
+)
+
+// 数据库迁移相关命令行
+type migrateAction struct{}
+
+var Migrate migrateAction
+
+// Up 执行未执行的迁移
+//
+//	demo-cli migrate up [--db demo] [--step n], step 为 0 执行全部.
+func (migrateAction) Up(c *cli.Context) error {
+	m, err := newMigrator(c.String("db"))
+	if err != nil {
+		return err
+	}
+	done, err := m.Up(c.Context, c.Int("step"))
+	for _, migration := range done {
+		fmt.Printf("已执行 %d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Println("没有需要执行的迁移")
+	}
+
+	return nil
+}
+
+// Down 回滚最近执行的迁移
+//
+//	demo-cli migrate down [--db demo] [--step n], step 默认为 1.
+func (migrateAction) Down(c *cli.Context) error {
+	m, err := newMigrator(c.String("db"))
+	if err != nil {
+		return err
+	}
+	done, err := m.Down(c.Context, c.Int("step"))
+	for _, migration := range done {
+		fmt.Printf("已回滚 %d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Println("没有可以回滚的迁移")
+	}
+
+	return nil
+}
+
+// Status 迁移状态
+func (migrateAction) Status(c *cli.Context) error {
+	m, err := newMigrator(c.String("db"))
+	if err != nil {
+		return err
+	}
+	statuses, err := m.Status(c.Context)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		state := "未执行"
+		appliedAt := ""
+		switch {
+		case status.Dirty:
+			state = "执行失败"
+		case status.Missing:
+			state = "文件缺失"
+		case status.Applied:
+			state = "已执行"
+		}
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%-8s %d_%-40s %s\n", state, status.Version, status.Name, appliedAt)
+	}
+
+	return nil
+}
+
+// Create 创建迁移文件
+//
+//	demo-cli migrate create [--db demo] <name>, 在 internal/migration/<db>/ 中创建, 需要在项目根目录执行.
+func (migrateAction) Create(c *cli.Context) error {
+	name := c.Args().Get(0)
+	if name == "" {
+		fmt.Println("请输入迁移名称, 比如 add_t_users_email")
+		return nil
+	}
+	up, down, err := migratex.Create(filepath.Join("internal", "migration", c.String("db")), name)
+	if err != nil {
+		return err
+	}
+	fmt.Println("已创建:")
+	fmt.Println(up)
+	fmt.Println(down)
+
+	return nil
+}
+
+func newMigrator(name string) (*migratex.Migrator, error) {
+	source, err := migration.FS(name)
+	if err != nil {
+		return nil, err
+	}
+	db := di.DB(name)
+	if db == nil {
+		return nil, errors.New("数据库 " + name + " 连接失败")
+	}
+
+	return migratex.New(db, source)
+}
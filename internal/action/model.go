@@ -0,0 +1,51 @@
+// Package action 命令行 action
+package action
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"go-demo/config/di"
+	"go-demo/pkg/genx"
+
+	"github.com/urfave/cli/v2"
+)
+
+// 表包含全部列时嵌入的通用列
+var modelMixins = []genx.Mixin{
+	{Type: "gormx.Audit", Import: "go-demo/pkg/gormx", Columns: []string{"created_by", "updated_by"}},
+	{Type: "gormx.Version", Import: "go-demo/pkg/gormx", Columns: []string{"version"}},
+	{Type: "gormx.SoftDelete", Import: "go-demo/pkg/gormx", Columns: []string{"deleted_at"}},
+}
+
+// Model 相关命令行
+type modelAction struct{}
+
+var Model modelAction
+
+// Gen 生成表 Model
+//
+//	demo-cli model gen --table t_users [--table t_orders] [--db demo], 从数据库读取表结构, 生成到 internal/model/<table>.go, 需要在项目根目录执行.
+//	文件已存在时只替换结构体, TableName() 与列名变量, 自定义的方法保留. 包含 created_by, updated_by, version, deleted_at 的表嵌入 gormx 的通用列.
+func (modelAction) Gen(c *cli.Context) error {
+	db := di.DB(c.String("db"))
+	if db == nil {
+		return errors.New("数据库 " + c.String("db") + " 连接失败")
+	}
+
+	dir := filepath.Join("internal", "model")
+	for _, name := range c.StringSlice("table") {
+		table, err := genx.ReadTable(c.Context, db, name)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, name+".go")
+		if err := genx.WriteModel(path, filepath.Base(dir), table, modelMixins...); err != nil {
+			return err
+		}
+		fmt.Printf("已生成 %s\n", path)
+	}
+
+	return nil
+}
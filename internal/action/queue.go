@@ -0,0 +1,441 @@
+// Package action 命令行 action
+package action
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-demo/config"
+	"go-demo/config/di"
+	"go-demo/internal/consts"
+	"go-demo/pkg/queuex"
+
+	"github.com/hibiken/asynq"
+	"github.com/samber/lo"
+	"github.com/urfave/cli/v2"
+)
+
+// 批量操作时每页查询的任务数
+const queuePageSize = 1000
+
+// 输出任务列表时 payload 的最大长度, 完整内容见 queue show
+const queuePayloadMaxLen = 500
+
+// 消息队列管理命令行, 基于 asynq Inspector, 连接 di 中消息队列使用的 redis
+type queueAction struct{}
+
+var Queue queueAction
+
+// List 队列列表, 包括大小, 延迟与今日处理数
+//
+//	配置中声明但未创建的队列显示为未创建; 未在配置中声明的队列权重为 -, 其中的任务不会被处理.
+func (queueAction) List(c *cli.Context) error {
+	inspector := di.QueueInspector()
+	weights := config.GetStringMapInt("queues")
+	names, err := inspector.Queues()
+	if err != nil {
+		return err
+	}
+	names = lo.Uniq(append(names, lo.Keys(weights)...))
+	sort.Strings(names)
+
+	fmt.Printf("%-12s %-6s %-6s %8s %8s %8s %8s %8s %8s %8s %10s %8s %8s\n",
+		"队列", "权重", "状态", "大小", "待处理", "处理中", "定时", "重试", "归档", "已完成", "延迟", "今日处理", "今日失败")
+	for _, name := range names {
+		weight := "-"
+		if w, ok := weights[name]; ok {
+			weight = fmt.Sprint(w)
+		}
+		info, err := inspector.GetQueueInfo(name)
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			fmt.Printf("%-12s %-6s %-6s\n", name, weight, "未创建")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		state := "运行"
+		if info.Paused {
+			state = "暂停"
+		}
+		fmt.Printf("%-12s %-6s %-6s %8d %8d %8d %8d %8d %8d %8d %10s %8d %8d\n",
+			name, weight, state, info.Size, info.Pending, info.Active, info.Scheduled, info.Retry, info.Archived, info.Completed,
+			info.Latency.Round(time.Millisecond), info.Processed, info.Failed)
+	}
+
+	return nil
+}
+
+// Tasks 任务列表, 包括 payload 与最近一次失败的原因
+//
+//	demo-cli queue tasks [--queue q] [--state retry,archived] [--type t] [--error e] [--limit 20], 默认列出全部队列中重试与归档 (失败) 的任务.
+func (queueAction) Tasks(c *cli.Context) error {
+	filter, ok := newTaskFilter(c)
+	if !ok {
+		return nil
+	}
+	limit := c.Int("limit")
+	tasks, err := filter.find(limit)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		printTask(t, queuePayloadMaxLen)
+	}
+	if len(tasks) == limit {
+		fmt.Printf("仅显示前 %d 个任务, 使用 --limit 调整\n", limit)
+	} else {
+		fmt.Printf("共 %d 个任务\n", len(tasks))
+	}
+
+	return nil
+}
+
+// Show 任务详情, 包括完整 payload 与 headers
+//
+//	demo-cli queue show [--queue q] <id>, 未指定队列时在全部队列中查找.
+func (queueAction) Show(c *cli.Context) error {
+	id := c.Args().Get(0)
+	if id == "" {
+		fmt.Println("请输入任务 ID")
+		return nil
+	}
+	t, err := findTask(c.String("queue"), id)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		fmt.Println("任务不存在")
+		return nil
+	}
+	printTask(t, 0)
+	for k, v := range t.Headers {
+		fmt.Printf("  header: %s=%s\n", k, v)
+	}
+
+	return nil
+}
+
+// Retry 立即执行重试, 归档或定时的任务
+func (queueAction) Retry(c *cli.Context) error {
+	return Queue.apply(c, "重试", []asynq.TaskState{asynq.TaskStateScheduled, asynq.TaskStateRetry, asynq.TaskStateArchived},
+		di.QueueInspector().RunTask,
+		map[asynq.TaskState]func(string) (int, error){
+			asynq.TaskStateScheduled: di.QueueInspector().RunAllScheduledTasks,
+			asynq.TaskStateRetry:     di.QueueInspector().RunAllRetryTasks,
+			asynq.TaskStateArchived:  di.QueueInspector().RunAllArchivedTasks,
+		})
+}
+
+// Archive 归档待处理, 定时或重试的任务, 归档的任务不再执行, 可以重试
+func (queueAction) Archive(c *cli.Context) error {
+	return Queue.apply(c, "归档", []asynq.TaskState{asynq.TaskStatePending, asynq.TaskStateScheduled, asynq.TaskStateRetry},
+		di.QueueInspector().ArchiveTask,
+		map[asynq.TaskState]func(string) (int, error){
+			asynq.TaskStatePending:   di.QueueInspector().ArchiveAllPendingTasks,
+			asynq.TaskStateScheduled: di.QueueInspector().ArchiveAllScheduledTasks,
+			asynq.TaskStateRetry:     di.QueueInspector().ArchiveAllRetryTasks,
+		})
+}
+
+// Delete 删除任务, 处理中的任务不能删除
+func (queueAction) Delete(c *cli.Context) error {
+	return Queue.apply(c, "删除", []asynq.TaskState{asynq.TaskStatePending, asynq.TaskStateScheduled, asynq.TaskStateRetry, asynq.TaskStateArchived, asynq.TaskStateCompleted},
+		di.QueueInspector().DeleteTask,
+		map[asynq.TaskState]func(string) (int, error){
+			asynq.TaskStatePending:   di.QueueInspector().DeleteAllPendingTasks,
+			asynq.TaskStateScheduled: di.QueueInspector().DeleteAllScheduledTasks,
+			asynq.TaskStateRetry:     di.QueueInspector().DeleteAllRetryTasks,
+			asynq.TaskStateArchived:  di.QueueInspector().DeleteAllArchivedTasks,
+			asynq.TaskStateCompleted: di.QueueInspector().DeleteAllCompletedTasks,
+		})
+}
+
+// apply 按 ID 或过滤条件批量操作任务
+//
+//	demo-cli queue <retry|archive|delete> [--queue q] <id...> 按 ID 操作;
+//	demo-cli queue <retry|archive|delete> [--queue q] [--state s] [--type t] [--error e] [--all] 按过滤条件操作, 没有 --type, --error 时需要 --all.
+func (queueAction) apply(c *cli.Context, name string, states []asynq.TaskState,
+	one func(queue, id string) error, all map[asynq.TaskState]func(queue string) (int, error)) error {
+	// 按 ID
+	if c.Args().Present() {
+		for _, id := range c.Args().Slice() {
+			t, err := findTask(c.String("queue"), id)
+			if err != nil {
+				return err
+			}
+			if t == nil {
+				fmt.Printf("任务 %s 不存在\n", id)
+				continue
+			}
+			if !lo.Contains(states, t.State) {
+				fmt.Printf("任务 %s 状态为 %s, 不能%s\n", id, t.State, name)
+				continue
+			}
+			if err := one(t.Queue, t.ID); err != nil {
+				return err
+			}
+			fmt.Printf("已%s %s %s\n", name, t.Queue, t.ID)
+		}
+		return nil
+	}
+
+	filter, ok := newTaskFilter(c)
+	if !ok {
+		return nil
+	}
+	for _, state := range filter.states {
+		if !lo.Contains(states, state) {
+			fmt.Printf("状态为 %s 的任务不能%s\n", state, name)
+			return nil
+		}
+	}
+	if filter.taskType == "" && filter.errContains == "" && !c.Bool("all") {
+		fmt.Println("请输入任务 ID, 或使用 --type, --error 过滤, 或使用 --all 操作全部任务")
+		return nil
+	}
+
+	// 没有过滤条件时整批操作
+	if filter.taskType == "" && filter.errContains == "" {
+		total := 0
+		for _, queue := range filter.queues {
+			for _, state := range filter.states {
+				n, err := all[state](queue)
+				if err != nil {
+					return err
+				}
+				total += n
+			}
+		}
+		fmt.Printf("已%s %d 个任务\n", name, total)
+		return nil
+	}
+
+	// 先查出全部匹配的任务再操作, 避免操作过程中分页变化
+	tasks, err := filter.find(0)
+	if err != nil {
+		return err
+	}
+	total := 0
+	for _, t := range tasks {
+		if err := one(t.Queue, t.ID); err != nil {
+			if errors.Is(err, asynq.ErrTaskNotFound) { // 已被处理
+				continue
+			}
+			return err
+		}
+		total++
+	}
+	fmt.Printf("已%s %d 个任务\n", name, total)
+
+	return nil
+}
+
+// Pause 暂停队列, Worker 不再从该队列拉取任务, 处理中的任务不受影响
+func (queueAction) Pause(c *cli.Context) error {
+	queue := c.Args().Get(0)
+	if queue == "" {
+		fmt.Println("请输入队列名称")
+		return nil
+	}
+	if err := di.QueueInspector().PauseQueue(queue); err != nil {
+		return err
+	}
+	fmt.Printf("已暂停 %s\n", queue)
+
+	return nil
+}
+
+// Unpause 恢复队列
+func (queueAction) Unpause(c *cli.Context) error {
+	queue := c.Args().Get(0)
+	if queue == "" {
+		fmt.Println("请输入队列名称")
+		return nil
+	}
+	if err := di.QueueInspector().UnpauseQueue(queue); err != nil {
+		return err
+	}
+	fmt.Printf("已恢复 %s\n", queue)
+
+	return nil
+}
+
+// Stats 按任务名的处理统计
+//
+//	demo-cli queue stats [--days 1], 由 demo-queue 的 queuex.StatsMiddleware 记录, 保留 7 天.
+func (queueAction) Stats(c *cli.Context) error {
+	days := c.Int("days")
+	if days < 1 || days > 7 {
+		fmt.Println("--days 取值为 1-7")
+		return nil
+	}
+	stats, err := queuex.ReadStats(c.Context, di.StorageRedis(), consts.QueueStats, days)
+	if err != nil {
+		return err
+	}
+	if len(stats) == 0 {
+		fmt.Println("没有处理记录")
+		return nil
+	}
+
+	fmt.Printf("%-30s %10s %10s %8s %12s\n", "任务名", "处理", "失败", "失败率", "平均耗时")
+	for _, s := range stats {
+		fmt.Printf("%-30s %10d %10d %7.2f%% %12s\n",
+			s.Type, s.Processed, s.Failed, float64(s.Failed)*100/float64(max(s.Processed, 1)), s.AvgElapsed())
+	}
+
+	return nil
+}
+
+// taskStates 可以查询的任务状态
+var taskStates = map[string]asynq.TaskState{
+	"pending":   asynq.TaskStatePending,
+	"active":    asynq.TaskStateActive,
+	"scheduled": asynq.TaskStateScheduled,
+	"retry":     asynq.TaskStateRetry,
+	"archived":  asynq.TaskStateArchived,
+	"completed": asynq.TaskStateCompleted,
+}
+
+// taskFilter 任务过滤条件
+type taskFilter struct {
+	queues      []string
+	states      []asynq.TaskState
+	taskType    string // 任务名, 完全匹配
+	errContains string // 最近一次失败的原因包含
+}
+
+// newTaskFilter 从 --queue, --state, --type, --error 创建过滤条件, 参数错误时输出提示并返回 false
+func newTaskFilter(c *cli.Context) (taskFilter, bool) {
+	filter := taskFilter{taskType: c.String("type"), errContains: c.String("error")}
+	if queue := c.String("queue"); queue != "" {
+		filter.queues = []string{queue}
+	} else {
+		queues, err := di.QueueInspector().Queues()
+		if err != nil {
+			fmt.Println(err.Error())
+			return filter, false
+		}
+		sort.Strings(queues)
+		filter.queues = queues
+	}
+	for _, name := range c.StringSlice("state") {
+		state, ok := taskStates[name]
+		if !ok {
+			fmt.Printf("任务状态 %s 不正确, 可选 pending, active, scheduled, retry, archived, completed\n", name)
+			return filter, false
+		}
+		filter.states = append(filter.states, state)
+	}
+
+	return filter, true
+}
+
+// find 查询匹配的任务, limit 为 0 时不限制数量
+func (f taskFilter) find(limit int) ([]*asynq.TaskInfo, error) {
+	result := make([]*asynq.TaskInfo, 0)
+	for _, queue := range f.queues {
+		for _, state := range f.states {
+			for page := 1; ; page++ {
+				tasks, err := listTasks(queue, state, page)
+				if errors.Is(err, asynq.ErrQueueNotFound) {
+					break
+				}
+				if err != nil {
+					return nil, err
+				}
+				for _, t := range tasks {
+					if f.match(t) {
+						result = append(result, t)
+						if limit > 0 && len(result) >= limit {
+							return result, nil
+						}
+					}
+				}
+				if len(tasks) < queuePageSize {
+					break
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (f taskFilter) match(t *asynq.TaskInfo) bool {
+	if f.taskType != "" && t.Type != f.taskType {
+		return false
+	}
+	if f.errContains != "" && !strings.Contains(t.LastErr, f.errContains) {
+		return false
+	}
+
+	return true
+}
+
+// listTasks 按状态分页查询任务
+func listTasks(queue string, state asynq.TaskState, page int) ([]*asynq.TaskInfo, error) {
+	inspector := di.QueueInspector()
+	opts := []asynq.ListOption{asynq.Page(page), asynq.PageSize(queuePageSize)}
+	switch state {
+	case asynq.TaskStatePending:
+		return inspector.ListPendingTasks(queue, opts...)
+	case asynq.TaskStateActive:
+		return inspector.ListActiveTasks(queue, opts...)
+	case asynq.TaskStateScheduled:
+		return inspector.ListScheduledTasks(queue, opts...)
+	case asynq.TaskStateRetry:
+		return inspector.ListRetryTasks(queue, opts...)
+	case asynq.TaskStateArchived:
+		return inspector.ListArchivedTasks(queue, opts...)
+	case asynq.TaskStateCompleted:
+		return inspector.ListCompletedTasks(queue, opts...)
+	}
+
+	return nil, fmt.Errorf("不支持的任务状态 %s", state)
+}
+
+// findTask 按 ID 查询任务, queue 为空时在全部队列中查找, 不存在时返回 nil
+func findTask(queue, id string) (*asynq.TaskInfo, error) {
+	inspector := di.QueueInspector()
+	queues := []string{queue}
+	if queue == "" {
+		var err error
+		if queues, err = inspector.Queues(); err != nil {
+			return nil, err
+		}
+	}
+	for _, q := range queues {
+		t, err := inspector.GetTaskInfo(q, id)
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+
+	return nil, nil
+}
+
+// printTask 输出任务, payloadMaxLen 为 0 时输出完整 payload
+func printTask(t *asynq.TaskInfo, payloadMaxLen int) {
+	fmt.Printf("%s %s %s %s 重试 %d/%d", t.Queue, t.State, t.ID, t.Type, t.Retried, t.MaxRetry)
+	if !t.NextProcessAt.IsZero() {
+		fmt.Printf(" 下次执行 %s", t.NextProcessAt.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Println()
+	if t.LastErr != "" {
+		fmt.Printf("  错误: %s (%s)\n", t.LastErr, t.LastFailedAt.Format("2006-01-02 15:04:05"))
+	}
+	payload := string(t.Payload)
+	if payloadMaxLen > 0 && len(payload) > payloadMaxLen {
+		payload = payload[:payloadMaxLen] + "..."
+	}
+	fmt.Printf("  payload: %s\n", payload)
+}
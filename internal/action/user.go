@@ -2,10 +2,17 @@
 package action
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"go-demo/config/di"
 	"go-demo/internal/model"
+	"go-demo/internal/service"
+	"go-demo/pkg/gormx"
 
 	"github.com/urfave/cli/v2"
 )
@@ -24,10 +31,86 @@ func (user) AddUser(c *cli.Context) error {
 		return nil
 	}
 
-	if err := di.DemoDB().Model(&model.TUsers{}).Create(map[string]any{"user_name": userName}).Error; err != nil {
+	err := di.DemoDB().Model(&model.TUsers{}).Create(map[string]any{"user_name": userName}).Error
+	if gormx.IsDuplicateKey(err) {
+		fmt.Println("用户名已存在")
+		return nil
+	}
+	if err != nil {
 		return err
 	}
 	fmt.Println("处理完毕")
 
 	return nil
 }
+
+// Import 从 CSV 或 XLSX 文件导入用户
+//
+//	由 demo-queue 逐批写入, 默认等待处理完成并输出进度, 有失败的行时写入同目录的 <文件名>.errors.csv.
+func (user) Import(c *cli.Context) error {
+	name := c.Args().Get(0)
+	if name == "" {
+		fmt.Println("请输入文件路径")
+		return nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	job, err := service.UserImport.Create(c.Context, name, f)
+	if errors.Is(err, service.ErrUserImportFile) {
+		fmt.Println(err.Error())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("导入任务 %d, 共 %d 行\n", job.ID, job.TotalRows)
+	if !c.Bool("wait") {
+		return nil
+	}
+
+	// 进度
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		fmt.Printf("\r已处理 %d/%d, 成功 %d, 失败 %d", job.ProcessedRows, job.TotalRows, job.SuccessRows, job.FailedRows)
+		if job.Status == service.UserImportDone {
+			fmt.Println()
+			break
+		}
+		select {
+		case <-c.Context.Done():
+			fmt.Println()
+			return c.Context.Err()
+		case <-ticker.C:
+		}
+		if job, err = service.UserImport.Get(c.Context, job.ID); err != nil {
+			return err
+		}
+	}
+
+	// 失败文件
+	if job.FailedRows == 0 {
+		fmt.Println("处理完毕")
+		return nil
+	}
+	errName := strings.TrimSuffix(name, filepath.Ext(name)) + ".errors.csv"
+	ef, err := os.Create(errName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = ef.Close()
+	}()
+	if err := service.UserImport.WriteErrors(c.Context, job.ID, ef); err != nil {
+		return err
+	}
+	fmt.Println("失败的行已写入 " + errName)
+
+	return nil
+}
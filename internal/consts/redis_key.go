@@ -10,3 +10,8 @@ const (
 const (
 	SubmitLimit = "submit:limit:%s" // 提交频率限制, submit:limit:<md5(id|ip&&agent+method+path)>
 )
+
+// 消息队列
+const (
+	QueueStats = "queue:stats:" // 任务处理统计, queue:stats:<yyyymmdd>, Hash, 见 queuex.StatsMiddleware
+)
@@ -0,0 +1,7 @@
+package consts
+
+// 消息队列任务名, 任务定义见 types.UserAddTask 等, 格式为 <业务>:<操作>
+const (
+	TaskUserAdd    = "User:AddUser" // 创建用户
+	TaskUserImport = "User:Import"  // 用户导入, 一个任务处理一批行
+)
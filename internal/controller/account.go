@@ -2,21 +2,45 @@
 package controller
 
 import (
-	"fmt"
+	"errors"
 	"strings"
+	"time"
 
 	"go-demo/config/di"
 	"go-demo/internal/consts"
 	"go-demo/internal/model"
 	"go-demo/internal/service"
 	"go-demo/pkg/ginx"
+	"go-demo/pkg/gormx"
 	"go-demo/pkg/gox"
 
-	"github.com/dromara/carbon/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
 	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// 事务中的业务错误, 用于回滚后返回对应的响应
+var (
+	errUserNotFound = errors.New("用户不存在")
+	errUserConflict = errors.New("用户名已存在")
+)
+
+// userDetail 用户详情响应, 按字段查询, 不查询与返回密码
+type userDetail struct {
+	UserID    int64     `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Position  float64   `json:"position"`
+	Money     float64   `json:"money"`
+	IsVip     int64     `json:"is_vip"`
+	UUID      string    `json:"uuid"`
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // 用户相关控制器 DEMO 这里定义一个空结构体用于为大量的 controller 方法做分类
 type account struct{}
 
@@ -35,7 +59,7 @@ func (account) PostUserLogin(c *gin.Context) {
 		UserName string `json:"user_name"`
 		Password string `json:"password"`
 	}{}
-	if err := di.DemoDB().Model(&model.TUsers{}).Where("user_name = ?", jsonBody["user_name"]).Limit(1).Find(&user).Error; err != nil {
+	if err := di.DemoDB().WithContext(c.Request.Context()).Model(&model.TUsers{}).Where("user_name = ?", jsonBody["user_name"]).Limit(1).Find(&user).Error; err != nil {
 		ginx.InternalError(c, nil)
 		return
 	}
@@ -106,8 +130,8 @@ func (account) GetUsersByID(c *gin.Context) {
 		return
 	}
 
-	user := model.TUsers{}
-	if err := di.DemoDB().Where("user_id = ?", userID).Find(&user).Error; err != nil {
+	user := userDetail{}
+	if err := di.DemoDB().WithContext(c.Request.Context()).Model(&model.TUsers{}).Scopes(gormx.Cache(time.Minute)).Where("user_id = ?", userID).Find(&user).Error; err != nil {
 		ginx.InternalError(c, nil)
 		return
 	}
@@ -130,25 +154,22 @@ func (account) PostUsers(c *gin.Context) {
 		userCount = cast.ToInt(jsonBody["user_count"])
 	}
 
-	// 多线程写 Demo
-	ch := make(chan error, userCount)
-	psg := di.PoolSeparate(100).Group()
+	// 批量写入 Demo, 每 500 行一条 INSERT. 用户名唯一, 以 UUID 生成, 重复时整条 INSERT 失败并逐行重试
+	users := make([]model.TUsers, 0, userCount)
 	for i := 0; i < userCount; i++ {
-		psg.Submit(func() {
-			user := model.TUsers{
-				UserName: fmt.Sprintf("U%d%d", carbon.Now().Timestamp(), gox.RandInt64(1111, 9999)),
-				Password: gox.PasswordHash("111111"),
-			}
-			if err := di.DemoDB().Create(&user).Error; err != nil {
-				ch <- err
-			}
+		users = append(users, model.TUsers{
+			UserName: "U" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+			Password: gox.PasswordHash("111111"),
 		})
 	}
-	psg.Wait()
-	close(ch)
-	okCount := userCount - len(ch)
+	result, err := gormx.BatchUpsert(c.Request.Context(), di.DemoDB(), users, gormx.UpsertOptions{})
+	if err != nil {
+		ginx.InternalError(c, err)
+		return
+	}
+	okCount := userCount - len(result.Failures)
 
-	ginx.Success(c, 201, gin.H{"ok_count": okCount})
+	ginx.Success(c, 201, gin.H{"ok_count": okCount, "user_ids": result.IDs})
 }
 
 func (account) PutUsersByID(c *gin.Context) {
@@ -157,48 +178,68 @@ func (account) PutUsersByID(c *gin.Context) {
 		return
 	}
 
-	jsonBody, err := ginx.GetJSONBody(c, []string{"user_name:用户名:string:?", "password:密码:string:?", "is_vip:VIP身份:[0,1]:?"})
+	jsonBody, err := ginx.GetJSONBody(c, []string{"user_name:用户名:string:?", "password:密码:string:?", "is_vip:VIP身份:[0,1]:?", "version:版本号:!-integer:?"})
 	if err != nil {
 		return
 	}
-	if len(jsonBody) == 0 {
+	if len(lo.OmitByKeys(jsonBody, []string{"version"})) == 0 {
 		ginx.Error(c, 400, "ParamError", "请至少传递一个参数")
 		return
 	}
 
-	user := struct {
-		UserID int64
-	}{}
-	if err := di.DemoDB().Model(&model.TUsers{}).Where("user_id = ?", userID).Find(&user).Error; err != nil {
-		ginx.InternalError(c, nil)
-		return
-	}
-	if user.UserID == 0 {
-		ginx.Error(c, 404, "UserNotFound", "用户不存在")
-		return
+	if password, ok := jsonBody["password"].(string); ok {
+		jsonBody["password"] = gox.PasswordHash(password)
 	}
 
-	if _, ok := jsonBody["user_name"]; ok {
-		conflictUser := struct {
+	// 检查与修改在同一事务中, 锁定用户与用户名, 避免并发修改为同一用户名. 传递 version 时与当前版本号不一致返回 409
+	ctx := c.Request.Context()
+	// 修改后的用户
+	updatedUser := userDetail{}
+	err = gormx.Transaction(ctx, di.DemoDB(), func(tx *gorm.DB) error {
+		user := struct {
 			UserID int64
 		}{}
-		if err := di.DemoDB().Model(&model.TUsers{}).Where("user_name = ? AND user_id != ?", jsonBody["user_name"], userID).Find(&conflictUser).Error; err != nil {
-			ginx.InternalError(c, nil)
-			return
+		if err := tx.Model(&model.TUsers{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).Find(&user).Error; err != nil {
+			return err
 		}
-		if conflictUser.UserID > 0 {
-			ginx.Error(c, 400, "UserConflict", "用户名已存在")
-			return
+		if user.UserID == 0 {
+			return errUserNotFound
 		}
-	}
-	if password, ok := jsonBody["password"].(string); ok {
-		jsonBody["password"] = gox.PasswordHash(password)
-	}
 
-	if err := di.DemoDB().Model(&model.TUsers{}).Where("user_id = ?", userID).Updates(jsonBody).Error; err != nil {
-		ginx.InternalError(c, nil)
+		if _, ok := jsonBody["user_name"]; ok {
+			conflictUser := struct {
+				UserID int64
+			}{}
+			if err := tx.Model(&model.TUsers{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_name = ? AND user_id != ?", jsonBody["user_name"], userID).Find(&conflictUser).Error; err != nil {
+				return err
+			}
+			if conflictUser.UserID > 0 {
+				return errUserConflict
+			}
+		}
+
+		query := tx.Model(&model.TUsers{}).Where("user_id = ?", userID)
+		if _, ok := jsonBody["version"]; !ok { // 未传递 version 时直接覆盖
+			query = query.Scopes(gormx.SkipVersionCheck)
+		}
+		if err := query.Updates(jsonBody).Error; err != nil {
+			return err
+		}
+
+		// 返回修改后的用户
+		return tx.Model(&model.TUsers{}).Where("user_id = ?", userID).Find(&updatedUser).Error
+	})
+	switch {
+	case errors.Is(err, errUserNotFound):
+		ginx.Error(c, 404, "UserNotFound", "用户不存在")
+		return
+	case errors.Is(err, errUserConflict), gormx.IsDuplicateKey(err): // 唯一键兜底, 包括软删除的用户
+		ginx.Error(c, 400, "UserConflict", "用户名已存在")
+		return
+	case err != nil:
+		ginx.DBError(c, err)
 		return
 	}
 
-	ginx.Success(c, 200, nil)
+	ginx.Success(c, 200, updatedUser)
 }
@@ -0,0 +1,173 @@
+package controller_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"go-demo/internal/model"
+	"go-demo/internal/router"
+	"go-demo/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+func TestGetUsers(t *testing.T) {
+	db := testutil.NewDemoDB(t)
+	for _, name := range []string{"alice", "bob", "alina"} {
+		if err := db.Create(&model.TUsers{UserName: name}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := gin.New()
+	router.Account(r)
+
+	tests := []struct {
+		name      string
+		url       string
+		wantCode  int
+		wantNames []string // 按 user_id 倒序
+	}{
+		{name: "全部", url: "/account/v1/users", wantCode: 200, wantNames: []string{"alina", "bob", "alice"}},
+		{name: "按名称搜索", url: "/account/v1/users?user_name=ali", wantCode: 200, wantNames: []string{"alina", "alice"}},
+		{name: "没有匹配", url: "/account/v1/users?user_name=carol", wantCode: 200, wantNames: []string{}},
+		{name: "分页", url: "/account/v1/users?page=2&per_page=2", wantCode: 200, wantNames: []string{"alice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d, body = %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			var body struct {
+				TotalResults int64 `json:"total_results"`
+				Items        []struct {
+					UserName string `json:"user_name"`
+				} `json:"items"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, 0, len(body.Items))
+			for _, item := range body.Items {
+				names = append(names, item.UserName)
+			}
+			if len(names) != len(tt.wantNames) {
+				t.Fatalf("items = %v, want %v", names, tt.wantNames)
+			}
+			for i := range names {
+				if names[i] != tt.wantNames[i] {
+					t.Fatalf("items = %v, want %v", names, tt.wantNames)
+				}
+			}
+		})
+	}
+}
+
+func TestGetUsersByID(t *testing.T) {
+	db := testutil.NewDemoDB(t)
+	if err := db.Create(&model.TUsers{UserName: "demo", Password: "hash"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	router.Account(r)
+
+	tests := []struct {
+		name     string
+		url      string
+		wantCode int
+		wantBody string // code 或 user_name
+	}{
+		{name: "存在", url: "/account/v1/users/1", wantCode: 200, wantBody: "demo"},
+		{name: "不存在", url: "/account/v1/users/2", wantCode: 404, wantBody: "UserNotFound"},
+		{name: "参数错误", url: "/account/v1/users/abc", wantCode: 400, wantBody: "ParamInvalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d, body = %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			var body struct {
+				Code     string `json:"code"`
+				UserName string `json:"user_name"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if got := body.Code + body.UserName; got != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body.String(), tt.wantBody)
+			}
+			if strings.Contains(w.Body.String(), "password") {
+				t.Errorf("返回了密码, body = %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPostUsers(t *testing.T) {
+	env := testutil.Setup(t)
+	r := gin.New()
+	router.Account(r)
+
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantCount int
+	}{
+		{name: "默认 100 个", body: `{}`, wantCode: 201, wantCount: 100},
+		{name: "指定数量", body: `{"user_count":3}`, wantCode: 201, wantCount: 3},
+		{name: "超过一条 INSERT", body: `{"user_count":501}`, wantCode: 201, wantCount: 501},
+		{name: "数量错误", body: `{"user_count":"abc"}`, wantCode: 400},
+	}
+	total := 0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.Redis.FlushAll() // 清空提交限制
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/account/v1/users", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d, body = %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != 201 {
+				return
+			}
+			var body struct {
+				OkCount int     `json:"ok_count"`
+				UserIDs []int64 `json:"user_ids"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.OkCount != tt.wantCount || len(body.UserIDs) != tt.wantCount {
+				t.Fatalf("ok_count = %d, user_ids = %d 个, want %d", body.OkCount, len(body.UserIDs), tt.wantCount)
+			}
+			for i, id := range body.UserIDs {
+				if id == 0 {
+					t.Fatalf("user_ids[%d] = 0", i)
+				}
+			}
+			total += tt.wantCount
+			var count int64
+			if err := env.DB.Model(&model.TUsers{}).Distinct("user_name").Count(&count).Error; err != nil {
+				t.Fatal(err)
+			}
+			if int(count) != total {
+				t.Errorf("用户名 %d 个, want %d", count, total)
+			}
+		})
+	}
+}
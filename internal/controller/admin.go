@@ -0,0 +1,227 @@
+// Package controller API 控制器
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-demo/config"
+	"go-demo/config/di"
+	"go-demo/internal/model"
+	"go-demo/internal/service"
+	"go-demo/pkg/ginx"
+	"go-demo/pkg/gormx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 管理相关控制器
+type admin struct{}
+
+var Admin admin
+
+func (admin) GetLogLevel(c *gin.Context) {
+	ginx.Success(c, 200, gin.H{"level": di.LogLevel()})
+}
+
+// PutLogLevel 修改日志级别
+//
+//	仅对处理请求的进程生效, 重启后恢复为配置的级别.
+func (admin) PutLogLevel(c *gin.Context) {
+	jsonBody, err := ginx.GetJSONBody(c, []string{`level:日志级别:["Debug","Info","Warn","Error"]:+`})
+	if err != nil {
+		return
+	}
+
+	if err := di.SetLogLevel(jsonBody["level"].(string)); err != nil {
+		ginx.InternalError(c, err)
+		return
+	}
+	di.Logger().Warn("日志级别已切换为 " + di.LogLevel())
+
+	ginx.Success(c, 200, gin.H{"level": di.LogLevel()})
+}
+
+// PostConfigReload 立即重新加载配置
+//
+//	仅对处理请求的进程生效. restart_required 为值有变化但不支持热更新的配置项, 需要重启生效.
+func (admin) PostConfigReload(c *gin.Context) {
+	result, err := config.Reload()
+	var validationErr config.ValidationError
+	if errors.As(err, &validationErr) {
+		ginx.Error(c, 422, "InvalidConfig", validationErr.Error())
+		return
+	}
+	if err != nil {
+		ginx.InternalError(c, err)
+		return
+	}
+
+	ginx.Success(c, 200, gin.H{"changed": result.Changed, "restart_required": result.RestartRequired})
+}
+
+// GetAuditLogs 审计日志
+//
+//	可按操作人, 表与主键, 操作时间范围筛选, 时间格式为 2006-01-02 15:04:05, 包含开始时间不包含结束时间.
+func (admin) GetAuditLogs(c *gin.Context) {
+	queries, err := ginx.GetQueries(c, []string{
+		`actor_type:操作人类型:["","user","admin","api_key","system"]:""`,
+		`actor_id:操作人ID:!-integer:0`,
+		`target_table:表名:string:""`,
+		`target_id:主键:string:""`,
+		`start_time:开始时间:string:""`,
+		`end_time:结束时间:string:""`,
+	})
+	if err != nil {
+		return
+	}
+
+	where := make([]string, 0)
+	bindParams := make([]any, 0)
+
+	if actorType := queries["actor_type"].(string); actorType != "" {
+		where = append(where, "actor_type = ?")
+		bindParams = append(bindParams, actorType)
+	}
+	if actorID := queries["actor_id"].(int64); actorID != 0 {
+		where = append(where, "actor_id = ?")
+		bindParams = append(bindParams, actorID)
+	}
+	if targetTable := queries["target_table"].(string); targetTable != "" {
+		where = append(where, "target_table = ?")
+		bindParams = append(bindParams, targetTable)
+	}
+	if targetID := queries["target_id"].(string); targetID != "" {
+		where = append(where, "target_id = ?")
+		bindParams = append(bindParams, targetID)
+	}
+	for _, q := range []struct{ key, name, cond string }{
+		{"start_time", "开始时间", "created_at >= ?"},
+		{"end_time", "结束时间", "created_at < ?"},
+	} {
+		value := queries[q.key].(string)
+		if value == "" {
+			continue
+		}
+		t, err := time.ParseInLocation(time.DateTime, value, time.Local)
+		if err != nil {
+			ginx.Error(c, 400, "ParamInvalid", q.name+"不正确")
+			return
+		}
+		where = append(where, q.cond)
+		bindParams = append(bindParams, t)
+	}
+
+	items := make([]struct {
+		ID          int64                   `json:"id"`
+		ActorType   string                  `json:"actor_type"`
+		ActorID     int64                   `json:"actor_id"`
+		Action      string                  `json:"action"`
+		TargetTable string                  `json:"target_table"`
+		TargetID    string                  `json:"target_id"`
+		Changes     map[string]gormx.Change `gorm:"serializer:json" json:"changes"`
+		IP          string                  `json:"ip"`
+		RequestID   string                  `json:"request_id"`
+		CreatedAt   time.Time               `json:"created_at"`
+	}, 0)
+	paging, err := ginx.Paginate(c, &items, ginx.PageQuery{
+		DB:         di.DemoDB(),
+		Model:      &model.TAuditLogs{},
+		Where:      strings.Join(where, " AND "),
+		BindParams: bindParams,
+		OrderBy:    "id DESC",
+	})
+	if err != nil {
+		return
+	}
+
+	ginx.PageSuccess(c, items, paging)
+}
+
+// 导入文件大小上限
+const userImportMaxSize = 10 << 20
+
+// PostUserImports 导入用户
+//
+//	multipart 表单 file 字段上传 CSV 或 XLSX 文件, 返回导入任务, 由 demo-queue 异步写入, 通过 GetUserImportsByID 查询进度.
+func (admin) PostUserImports(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		ginx.Error(c, 400, "ParamEmpty", "文件不得为空")
+		return
+	}
+	if file.Size > userImportMaxSize {
+		ginx.Error(c, 400, "ParamInvalid", "文件不能超过 10MB")
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		ginx.InternalError(c, err)
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	job, err := service.UserImport.Create(c.Request.Context(), file.Filename, f)
+	if errors.Is(err, service.ErrUserImportFile) {
+		ginx.Error(c, 400, "ImportFileInvalid", err.Error())
+		return
+	}
+	if err != nil {
+		ginx.InternalError(c, nil)
+		return
+	}
+
+	ginx.Success(c, 201, job)
+}
+
+// GetUserImportsByID 导入进度
+func (admin) GetUserImportsByID(c *gin.Context) {
+	job, ok := findUserImport(c)
+	if !ok {
+		return
+	}
+
+	ginx.Success(c, 200, job)
+}
+
+// GetUserImportErrors 下载导入失败的行, CSV 文件
+func (admin) GetUserImportErrors(c *gin.Context) {
+	job, ok := findUserImport(c)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := service.UserImport.WriteErrors(c.Request.Context(), job.ID, &buf); err != nil {
+		ginx.InternalError(c, nil)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-import-%d-errors.csv"`, job.ID))
+	c.Data(200, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// findUserImport 按路径参数查询导入任务, 不存在时输出 404
+func findUserImport(c *gin.Context) (*model.TUserImports, bool) {
+	importID, err := ginx.FilterParam(c, "导入任务id", c.Param("import_id"), "+integer", false)
+	if err != nil {
+		return nil, false
+	}
+
+	job, err := service.UserImport.Get(c.Request.Context(), importID.(int64))
+	if err != nil {
+		ginx.InternalError(c, nil)
+		return nil, false
+	}
+	if job.ID == 0 {
+		ginx.Error(c, 404, "UserImportNotFound", "导入任务不存在")
+		return nil, false
+	}
+
+	return job, true
+}
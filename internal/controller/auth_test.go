@@ -0,0 +1,74 @@
+package controller_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-demo/config"
+	"go-demo/internal/consts"
+	"go-demo/internal/model"
+	"go-demo/internal/router"
+	"go-demo/internal/testutil"
+	"go-demo/pkg/gox"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostUserLogin(t *testing.T) {
+	env := testutil.Setup(t)
+	if err := env.DB.Create(&model.TUsers{UserName: "demo", Password: gox.PasswordHash("111111")}).Error; err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	router.Account(r)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "成功", body: `{"user_name":"demo","password":"111111"}`, wantCode: 200},
+		{name: "密码错误", body: `{"user_name":"demo","password":"222222"}`, wantCode: 400},
+		{name: "用户不存在", body: `{"user_name":"nobody","password":"111111"}`, wantCode: 400},
+		{name: "缺少参数", body: `{"user_name":"demo"}`, wantCode: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.Redis.FlushAll() // 清空提交限制
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/account/v1/login", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d, body = %s", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteUserLogout(t *testing.T) {
+	env := testutil.Setup(t)
+	r := gin.New()
+	router.Account(r)
+	authorization := testutil.Login(t, consts.UserJWT, 1, "demo")
+	jwtDB := env.Redis.DB(config.GetInt("redis_index_jwt"))
+	if keys := jwtDB.Keys(); len(keys) != 1 {
+		t.Fatalf("登录白名单 = %v, want 1 个", keys)
+	}
+
+	// 第二次登录凭证已失效
+	for _, wantCode := range []int{204, 401} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/account/v1/logout", nil)
+		req.Header.Set("Authorization", authorization)
+		r.ServeHTTP(w, req)
+		if w.Code != wantCode {
+			t.Fatalf("code = %d, want %d, body = %s", w.Code, wantCode, w.Body.String())
+		}
+	}
+	if keys := jwtDB.Keys(); len(keys) != 0 {
+		t.Errorf("退出后登录白名单 = %v, want 空", keys)
+	}
+}
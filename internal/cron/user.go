@@ -14,7 +14,7 @@ var User user
 
 // DeleteUsers 批量删除用户
 //
-//	userCount 为需要删除的数量.
+//	userCount 为需要删除的数量. TUsers 嵌入了 gormx.SoftDelete, 为软删除.
 func (user) DeleteUsers(userCount int) {
 	userIDs := make([]int64, 0)
 	if err := di.DemoDB().Model(&model.TUsers{}).Select("user_id").Order("user_id").Limit(userCount).Find(&userIDs).Error; err != nil {
@@ -2,20 +2,15 @@
 package middleware
 
 import (
-	"context"
-	"fmt"
 	"math"
 
-	"go-demo/config"
-	"go-demo/config/di"
 	"go-demo/internal/consts"
+	"go-demo/internal/service"
 	"go-demo/pkg/ginx"
-	"go-demo/pkg/gox"
+	"go-demo/pkg/gormx"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/samber/lo"
-	"github.com/spf13/cast"
 )
 
 // JWTParse JWT 解析
@@ -24,36 +19,19 @@ import (
 func JWTParse(userType string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := lo.Substring(c.Request.Header.Get("Authorization"), 7, math.MaxUint) // Authorization: Bearer <token>
-		// JWT校验
-		jwtToken, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-			return []byte(config.GetString("jwt_secret")), nil
-		})
-		if err != nil { // token 无效
-			c.Next()
-			return
-		}
-		claims, ok := jwtToken.Claims.(jwt.MapClaims)
-		if !ok || !jwtToken.Valid { // token 秘钥/时间等校验未通过
-			c.Next()
-			return
-		}
-		// 白名单校验
-		key := fmt.Sprintf(consts.JWTLogin, userType, claims["jti"], gox.MD5(tokenString))
-		if n, err := di.JWTRedis().Exists(context.Background(), key).Result(); err != nil {
-			di.Logger().Error(err.Error())
-			c.Next()
-			return
-		} else if n == 0 { // 不在白名单内
+		// JWT 与白名单校验
+		id := service.Auth.JWTVerify(c.Request.Context(), userType, tokenString)
+		if id == 0 {
 			c.Next()
 			return
 		}
 		// id 存入 Gin 上下文
-		id := cast.ToInt64(claims["jti"])
 		if userType == consts.UserJWT {
 			c.Set("userID", id) // 后续的处理函数可以用过 c.GetInt64("userID") 来获取当前请求的用户 id
 		} else if userType == consts.AdminJWT {
 			c.Set("adminID", id) // 后续的处理函数可以用过 c.GetInt64("adminID") 来获取当前请求的用户 id
 		}
+		c.Request = c.Request.WithContext(gormx.WithActor(c.Request.Context(), gormx.Actor{Type: userType, ID: id})) // 操作人, 写入 created_by, updated_by 与审计日志
 		c.Next()
 	}
 }
@@ -70,3 +48,16 @@ func UserAuth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminAuth 管理员鉴权
+//
+//	登录即可.
+func AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetInt64("adminID") == 0 {
+			ginx.Error(c, 401, "AdminUnauthorized", "您未登录或登录已过期, 请重新登录")
+			return
+		}
+		c.Next()
+	}
+}
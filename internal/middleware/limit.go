@@ -2,8 +2,8 @@
 package middleware
 
 import (
-	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"go-demo/config/di"
@@ -19,10 +19,31 @@ import (
 
 // QPSLimit QPS 限流
 func QPSLimit(qps int) gin.HandlerFunc {
+	return NewQPSLimiter(qps).Handler()
+}
+
+// QPSLimiter QPS 限流器, 限额可在运行时修改
+type QPSLimiter struct {
+	bucket atomic.Pointer[ratelimit.Bucket]
+}
+
+// NewQPSLimiter 创建 QPS 限流器
+func NewQPSLimiter(qps int) *QPSLimiter {
+	l := &QPSLimiter{}
+	l.SetQPS(qps)
+	return l
+}
+
+// SetQPS 修改限额
+func (l *QPSLimiter) SetQPS(qps int) {
 	quantum := cast.ToInt64(qps)
-	bucket := ratelimit.NewBucketWithQuantum(time.Second, quantum, quantum)
+	l.bucket.Store(ratelimit.NewBucketWithQuantum(time.Second, quantum, quantum))
+}
+
+// Handler QPS 限流中间件
+func (l *QPSLimiter) Handler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if bucket.TakeAvailable(1) < 1 {
+		if l.bucket.Load().TakeAvailable(1) < 1 {
 			ginx.Error(c, 429, "TooManyRequests", "服务繁忙, 请稍后重试")
 			return
 		}
@@ -46,7 +67,7 @@ func SubmitLimit() gin.HandlerFunc {
 		}
 		uid = gox.MD5(uid + ":" + c.Request.Method + ":" + c.Request.URL.Path)
 		key := fmt.Sprintf(consts.SubmitLimit, uid)
-		ok, err := di.CacheRedis().SetNX(context.Background(), key, 1, 2*time.Second).Result() // 2秒/次
+		ok, err := di.CacheRedis().SetNX(c.Request.Context(), key, 1, 2*time.Second).Result() // 2秒/次
 		if err != nil {
 			ginx.InternalError(c, err)
 			return
@@ -61,9 +82,34 @@ func SubmitLimit() gin.HandlerFunc {
 
 // Timeout 超时控制
 func Timeout(t time.Duration) gin.HandlerFunc {
-	return timeout.Timeout(
+	return NewTimeoutControl(t).Handler()
+}
+
+// TimeoutControl 超时控制, 超时时间可在运行时修改
+type TimeoutControl struct {
+	handler atomic.Pointer[gin.HandlerFunc]
+}
+
+// NewTimeoutControl 创建超时控制
+func NewTimeoutControl(t time.Duration) *TimeoutControl {
+	tc := &TimeoutControl{}
+	tc.SetTimeout(t)
+	return tc
+}
+
+// SetTimeout 修改超时时间, 对之后的请求生效
+func (tc *TimeoutControl) SetTimeout(t time.Duration) {
+	handler := timeout.Timeout(
 		timeout.WithTimeout(t),
 		timeout.WithErrorHttpCode(408), // optional
 		timeout.WithDefaultMsg(`{"code": "RequestTimeout", "message":"请求超时, 请稍后重试"}`), // optional
 	)
+	tc.handler.Store(&handler)
+}
+
+// Handler 超时控制中间件
+func (tc *TimeoutControl) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		(*tc.handler.Load())(c)
+	}
 }
@@ -0,0 +1,54 @@
+// Package middleware Gin 中间件
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"go-demo/pkg/gormx"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace 链路追踪
+//
+//	每个请求生成一个 span, 并从请求头还原上游的链路信息. 处理函数中使用 c.Request.Context() 向下传递.
+func Trace() gin.HandlerFunc {
+	return otelgin.Middleware(filepath.Base(os.Args[0]))
+}
+
+// 请求 ID 请求头, 上游传入时沿用
+const requestIDHeader = "X-Request-Id"
+
+var requestIDRegexp = regexp.MustCompile(`^[\w\-.:]{1,64}$`)
+
+// RequestID 请求 ID
+//
+//	优先使用上游传入的 X-Request-Id, 没有时使用链路 trace id, 未开启链路追踪时随机生成, 并写入响应头.
+//	请求 ID 与客户端 IP 写入 c.Request.Context(), 用于审计日志, 需要在 Trace 之后使用.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !requestIDRegexp.MatchString(requestID) {
+			if spanCtx := trace.SpanContextFromContext(c.Request.Context()); spanCtx.HasTraceID() {
+				requestID = spanCtx.TraceID().String()
+			} else {
+				b := make([]byte, 16)
+				_, _ = rand.Read(b)
+				requestID = hex.EncodeToString(b)
+			}
+		}
+		c.Set("requestID", requestID)
+		c.Header(requestIDHeader, requestID)
+		c.Request = c.Request.WithContext(gormx.WithRequestInfo(c.Request.Context(), gormx.RequestInfo{
+			IP:        c.ClientIP(),
+			RequestID: requestID,
+		}))
+		c.Next()
+	}
+}
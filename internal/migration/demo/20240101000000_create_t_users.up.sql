@@ -0,0 +1,14 @@
+-- 用户表
+CREATE TABLE IF NOT EXISTS `t_users` (
+  `user_id` bigint NOT NULL AUTO_INCREMENT,
+  `user_name` varchar(50) NOT NULL DEFAULT '' COMMENT '用户名',
+  `password` char(38) NOT NULL DEFAULT '' COMMENT '密码',
+  `position` float NOT NULL DEFAULT '0' COMMENT '位置',
+  `money` decimal(10,2) NOT NULL DEFAULT '0.00' COMMENT '金额',
+  `is_vip` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否VIP,1-是,0-否',
+  `uuid` varchar(50) NOT NULL DEFAULT '' COMMENT 'UUID',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`user_id`),
+  KEY `idx_user_name` (`user_name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户表';
@@ -0,0 +1,6 @@
+ALTER TABLE `t_users`
+  DROP KEY `idx_deleted_at`,
+  DROP COLUMN `deleted_at`,
+  DROP COLUMN `version`,
+  DROP COLUMN `updated_by`,
+  DROP COLUMN `created_by`;
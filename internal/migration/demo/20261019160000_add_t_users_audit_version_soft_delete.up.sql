@@ -0,0 +1,7 @@
+-- 操作人, 乐观锁, 软删除, 对应 gormx.Audit, gormx.Version, gormx.SoftDelete
+ALTER TABLE `t_users`
+  ADD COLUMN `created_by` bigint NOT NULL DEFAULT '0' COMMENT '创建人',
+  ADD COLUMN `updated_by` bigint NOT NULL DEFAULT '0' COMMENT '修改人',
+  ADD COLUMN `version` bigint NOT NULL DEFAULT '0' COMMENT '版本号',
+  ADD COLUMN `deleted_at` timestamp NULL DEFAULT NULL,
+  ADD KEY `idx_deleted_at` (`deleted_at`);
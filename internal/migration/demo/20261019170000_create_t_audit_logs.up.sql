@@ -0,0 +1,17 @@
+-- 审计日志, 由 gormx 审计插件记录, demo-queue 异步写入
+CREATE TABLE IF NOT EXISTS `t_audit_logs` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `actor_type` varchar(20) NOT NULL DEFAULT '' COMMENT '操作人类型,user-用户,admin-管理员,api_key-API Key,system-系统',
+  `actor_id` bigint NOT NULL DEFAULT '0' COMMENT '操作人ID',
+  `action` varchar(10) NOT NULL DEFAULT '' COMMENT '操作,create-创建,update-修改,delete-删除',
+  `target_table` varchar(64) NOT NULL DEFAULT '' COMMENT '表名',
+  `target_id` varchar(64) NOT NULL DEFAULT '' COMMENT '主键',
+  `changes` json NOT NULL COMMENT '修改的列,{"列名":{"before":修改前,"after":修改后}}',
+  `ip` varchar(45) NOT NULL DEFAULT '' COMMENT 'IP',
+  `request_id` varchar(64) NOT NULL DEFAULT '' COMMENT '请求ID',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '操作时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_actor` (`actor_type`, `actor_id`, `created_at`),
+  KEY `idx_target` (`target_table`, `target_id`, `created_at`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='审计日志';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `t_user_import_errors`;
+DROP TABLE IF EXISTS `t_user_imports`;
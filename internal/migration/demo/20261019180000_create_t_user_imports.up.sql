@@ -0,0 +1,28 @@
+-- 用户导入任务, 导入文件逐批由 demo-queue 处理
+CREATE TABLE IF NOT EXISTS `t_user_imports` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `file_name` varchar(255) NOT NULL DEFAULT '' COMMENT '文件名',
+  `status` varchar(20) NOT NULL DEFAULT 'processing' COMMENT '状态,processing-处理中,done-已完成',
+  `total_rows` int NOT NULL DEFAULT '0' COMMENT '总行数',
+  `processed_rows` int NOT NULL DEFAULT '0' COMMENT '已处理行数',
+  `success_rows` int NOT NULL DEFAULT '0' COMMENT '成功行数',
+  `failed_rows` int NOT NULL DEFAULT '0' COMMENT '失败行数',
+  `created_by` bigint NOT NULL DEFAULT '0' COMMENT '创建人',
+  `updated_by` bigint NOT NULL DEFAULT '0' COMMENT '修改人',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户导入任务';
+
+-- 用户导入失败的行
+CREATE TABLE IF NOT EXISTS `t_user_import_errors` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `import_id` bigint NOT NULL DEFAULT '0' COMMENT '导入任务ID',
+  `row_no` int NOT NULL DEFAULT '0' COMMENT '文件中的行号,表头为第1行',
+  `data` json NOT NULL COMMENT '行数据,{"列名":"值"},不包含密码',
+  `reason` varchar(255) NOT NULL DEFAULT '' COMMENT '失败原因',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_import_id` (`import_id`, `row_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户导入失败的行';
@@ -0,0 +1,16 @@
+-- 事务发件箱, 任务与业务数据在同一事务中写入, 由 demo-queue 投递到消息队列
+CREATE TABLE IF NOT EXISTS `t_outbox` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `task_name` varchar(100) NOT NULL DEFAULT '' COMMENT '任务名',
+  `payload` mediumtext NOT NULL COMMENT 'payload,JSON',
+  `headers` text NOT NULL COMMENT '任务headers,链路信息,JSON',
+  `options` text NOT NULL COMMENT '发送参数,JSON',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '状态,0-待投递,1-已投递,2-无法投递',
+  `attempts` int NOT NULL DEFAULT '0' COMMENT '投递失败次数',
+  `last_error` varchar(255) NOT NULL DEFAULT '' COMMENT '最近一次投递失败的原因',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_status` (`status`, `id`),
+  KEY `idx_updated_at` (`updated_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='事务发件箱';
@@ -0,0 +1,3 @@
+ALTER TABLE `t_users`
+  DROP KEY `uk_user_name`,
+  ADD KEY `idx_user_name` (`user_name`);
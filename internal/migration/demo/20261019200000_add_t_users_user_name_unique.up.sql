@@ -0,0 +1,9 @@
+-- 用户名唯一, 并发创建或修改为同一用户名时由唯一键拦截. 软删除的用户仍占用用户名
+-- 已有的重复用户名保留 user_id 最小的一行, 其余改为 <用户名>#<user_id>
+UPDATE `t_users` u
+  JOIN (SELECT `user_name`, MIN(`user_id`) AS `keep_id` FROM `t_users` GROUP BY `user_name` HAVING COUNT(*) > 1) d
+    ON u.`user_name` = d.`user_name` AND u.`user_id` != d.`keep_id`
+  SET u.`user_name` = CONCAT(LEFT(u.`user_name`, 29), '#', u.`user_id`);
+ALTER TABLE `t_users`
+  DROP KEY `idx_user_name`,
+  ADD UNIQUE KEY `uk_user_name` (`user_name`);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `t_user_import_batches`;
@@ -0,0 +1,9 @@
+-- 用户导入已处理的批次, 与该批的写入在同一事务中记录, 任务重复投递或提交后重试时跳过
+CREATE TABLE IF NOT EXISTS `t_user_import_batches` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `import_id` bigint NOT NULL DEFAULT '0' COMMENT '导入任务ID',
+  `row_no` int NOT NULL DEFAULT '0' COMMENT '批次第一行的行号',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_import_id_row_no` (`import_id`, `row_no`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户导入已处理的批次';
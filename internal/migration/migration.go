@@ -0,0 +1,19 @@
+// Package migration 数据库迁移文件
+//
+//	每个数据库一个目录 internal/migration/<name>/, 使用 demo-cli migrate create 创建, 编译进程序, demo-cli migrate up 执行.
+package migration
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// 迁移文件, 新增数据库时需要在此添加目录
+//
+//go:embed demo
+var files embed.FS
+
+// FS 数据库的迁移文件
+func FS(name string) (fs.FS, error) {
+	return fs.Sub(files, name)
+}
@@ -0,0 +1,18 @@
+// Package model 表 Model
+//
+//	由 demo-cli model gen 生成, 见 genx.WriteModel.
+package model
+
+import "go-demo/pkg/outbox"
+
+// Models 全部表 Model, 测试时用于在 SQLite 中创建表, 新增表后需要在此添加
+func Models() []any {
+	return []any{
+		&TUsers{},
+		&TAuditLogs{},
+		&TUserImports{},
+		&TUserImportErrors{},
+		&TUserImportBatches{},
+		&outbox.Message{},
+	}
+}
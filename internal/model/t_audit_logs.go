@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+)
+
+// TAuditLogs 审计日志
+type TAuditLogs struct {
+	ID          int64     `gorm:"primaryKey;column:id;type:bigint;not null" json:"id"`
+	ActorType   string    `gorm:"column:actor_type;type:varchar(20);not null;default:''" json:"actor_type"`              // 操作人类型,user-用户,admin-管理员,api_key-API Key,system-系统
+	ActorID     int64     `gorm:"column:actor_id;type:bigint;not null;default:0" json:"actor_id"`                        // 操作人ID
+	Action      string    `gorm:"column:action;type:varchar(10);not null;default:''" json:"action"`                      // 操作,create-创建,update-修改,delete-删除
+	TargetTable string    `gorm:"column:target_table;type:varchar(64);not null;default:''" json:"target_table"`          // 表名
+	TargetID    string    `gorm:"column:target_id;type:varchar(64);not null;default:''" json:"target_id"`                // 主键
+	Changes     string    `gorm:"column:changes;type:json;not null" json:"changes"`                                      // 修改的列,{"列名":{"before":修改前,"after":修改后}}
+	IP          string    `gorm:"column:ip;type:varchar(45);not null;default:''" json:"ip"`                              // IP
+	RequestID   string    `gorm:"column:request_id;type:varchar(64);not null;default:''" json:"request_id"`              // 请求ID
+	CreatedAt   time.Time `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"created_at"` // 操作时间
+}
+
+// TableName get sql table name.获取数据库表名
+func (m *TAuditLogs) TableName() string {
+	return "t_audit_logs"
+}
+
+// TAuditLogsColumns get sql column name.获取数据库列名
+var TAuditLogsColumns = struct {
+	ID          string
+	ActorType   string
+	ActorID     string
+	Action      string
+	TargetTable string
+	TargetID    string
+	Changes     string
+	IP          string
+	RequestID   string
+	CreatedAt   string
+}{
+	ID:          "id",
+	ActorType:   "actor_type",
+	ActorID:     "actor_id",
+	Action:      "action",
+	TargetTable: "target_table",
+	TargetID:    "target_id",
+	Changes:     "changes",
+	IP:          "ip",
+	RequestID:   "request_id",
+	CreatedAt:   "created_at",
+}
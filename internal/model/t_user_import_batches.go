@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+)
+
+// TUserImportBatches 用户导入已处理的批次
+type TUserImportBatches struct {
+	ID        int64     `gorm:"primaryKey;column:id;type:bigint;not null" json:"id"`
+	ImportID  int64     `gorm:"column:import_id;type:bigint;not null;default:0;uniqueIndex:uk_import_id_row_no" json:"import_id"` // 导入任务ID
+	RowNo     int64     `gorm:"column:row_no;type:int;not null;default:0;uniqueIndex:uk_import_id_row_no" json:"row_no"`          // 批次第一行的行号
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName get sql table name.获取数据库表名
+func (m *TUserImportBatches) TableName() string {
+	return "t_user_import_batches"
+}
+
+// TUserImportBatchesColumns get sql column name.获取数据库列名
+var TUserImportBatchesColumns = struct {
+	ID        string
+	ImportID  string
+	RowNo     string
+	CreatedAt string
+}{
+	ID:        "id",
+	ImportID:  "import_id",
+	RowNo:     "row_no",
+	CreatedAt: "created_at",
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+)
+
+// TUserImportErrors 用户导入失败的行
+type TUserImportErrors struct {
+	ID        int64     `gorm:"primaryKey;column:id;type:bigint;not null" json:"id"`
+	ImportID  int64     `gorm:"column:import_id;type:bigint;not null;default:0" json:"import_id"`  // 导入任务ID
+	RowNo     int64     `gorm:"column:row_no;type:int;not null;default:0" json:"row_no"`           // 文件中的行号,表头为第1行
+	Data      string    `gorm:"column:data;type:json;not null" json:"data"`                        // 行数据,{"列名":"值"},不包含密码
+	Reason    string    `gorm:"column:reason;type:varchar(255);not null;default:''" json:"reason"` // 失败原因
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName get sql table name.获取数据库表名
+func (m *TUserImportErrors) TableName() string {
+	return "t_user_import_errors"
+}
+
+// TUserImportErrorsColumns get sql column name.获取数据库列名
+var TUserImportErrorsColumns = struct {
+	ID        string
+	ImportID  string
+	RowNo     string
+	Data      string
+	Reason    string
+	CreatedAt string
+}{
+	ID:        "id",
+	ImportID:  "import_id",
+	RowNo:     "row_no",
+	Data:      "data",
+	Reason:    "reason",
+	CreatedAt: "created_at",
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"go-demo/pkg/gormx"
+)
+
+// TUserImports 用户导入任务
+type TUserImports struct {
+	ID            int64     `gorm:"primaryKey;column:id;type:bigint;not null" json:"id"`
+	FileName      string    `gorm:"column:file_name;type:varchar(255);not null;default:''" json:"file_name"`    // 文件名
+	Status        string    `gorm:"column:status;type:varchar(20);not null;default:'processing'" json:"status"` // 状态,processing-处理中,done-已完成
+	TotalRows     int64     `gorm:"column:total_rows;type:int;not null;default:0" json:"total_rows"`            // 总行数
+	ProcessedRows int64     `gorm:"column:processed_rows;type:int;not null;default:0" json:"processed_rows"`    // 已处理行数
+	SuccessRows   int64     `gorm:"column:success_rows;type:int;not null;default:0" json:"success_rows"`        // 成功行数
+	FailedRows    int64     `gorm:"column:failed_rows;type:int;not null;default:0" json:"failed_rows"`          // 失败行数
+	CreatedAt     time.Time `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	gormx.Audit
+}
+
+// TableName get sql table name.获取数据库表名
+func (m *TUserImports) TableName() string {
+	return "t_user_imports"
+}
+
+// TUserImportsColumns get sql column name.获取数据库列名
+var TUserImportsColumns = struct {
+	ID            string
+	FileName      string
+	Status        string
+	TotalRows     string
+	ProcessedRows string
+	SuccessRows   string
+	FailedRows    string
+	CreatedAt     string
+	UpdatedAt     string
+	CreatedBy     string
+	UpdatedBy     string
+}{
+	ID:            "id",
+	FileName:      "file_name",
+	Status:        "status",
+	TotalRows:     "total_rows",
+	ProcessedRows: "processed_rows",
+	SuccessRows:   "success_rows",
+	FailedRows:    "failed_rows",
+	CreatedAt:     "created_at",
+	UpdatedAt:     "updated_at",
+	CreatedBy:     "created_by",
+	UpdatedBy:     "updated_by",
+}
@@ -2,19 +2,24 @@ package model
 
 import (
 	"time"
+
+	"go-demo/pkg/gormx"
 )
 
 // TUsers 用户表
 type TUsers struct {
 	UserID    int64     `gorm:"primaryKey;column:user_id;type:bigint;not null" json:"user_id"`
-	UserName  string    `gorm:"column:user_name;type:varchar(50);not null;default:''" json:"user_name"` // 用户名
-	Password  string    `gorm:"column:password;type:char(38);not null;default:''" json:"password"`      // 密码
-	Position  float64   `gorm:"column:position;type:float;not null;default:0" json:"position"`          // 位置
-	Money     float64   `gorm:"column:money;type:decimal(10,2);not null;default:0.00" json:"money"`     // 金额
-	IsVip     int64     `gorm:"column:is_vip;type:tinyint(1);not null;default:0" json:"is_vip"`         // 是否VIP,1-是,0-否
-	UUID      string    `gorm:"column:uuid;type:varchar(50);not null;default:''" json:"uuid"`           // UUID
+	UserName  string    `gorm:"column:user_name;type:varchar(50);not null;unique;default:''" json:"user_name"` // 用户名
+	Password  string    `gorm:"column:password;type:char(38);not null;default:''" json:"password"`             // 密码
+	Position  float64   `gorm:"column:position;type:float;not null;default:0" json:"position"`                 // 位置
+	Money     float64   `gorm:"column:money;type:decimal(10,2);not null;default:0.00" json:"money"`            // 金额
+	IsVip     int64     `gorm:"column:is_vip;type:tinyint(1);not null;default:0" json:"is_vip"`                // 是否VIP,1-是,0-否
+	UUID      string    `gorm:"column:uuid;type:varchar(50);not null;default:''" json:"uuid"`                  // UUID
 	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	gormx.Audit
+	gormx.Version
+	gormx.SoftDelete
 }
 
 // TableName get sql table name.获取数据库表名
@@ -33,6 +38,10 @@ var TUsersColumns = struct {
 	UUID      string
 	CreatedAt string
 	UpdatedAt string
+	CreatedBy string
+	UpdatedBy string
+	Version   string
+	DeletedAt string
 }{
 	UserID:    "user_id",
 	UserName:  "user_name",
@@ -43,4 +52,8 @@ var TUsersColumns = struct {
 	UUID:      "uuid",
 	CreatedAt: "created_at",
 	UpdatedAt: "updated_at",
+	CreatedBy: "created_by",
+	UpdatedBy: "updated_by",
+	Version:   "version",
+	DeletedAt: "deleted_at",
 }
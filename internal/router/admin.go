@@ -0,0 +1,31 @@
+// Package router API 路由
+package router
+
+import (
+	"go-demo/internal/consts"
+	"go-demo/internal/controller"
+	"go-demo/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin 管理模块
+func Admin(r *gin.Engine) {
+	adminGroup := r.Group("/admin/v1", middleware.JWTParse(consts.AdminJWT), middleware.AdminAuth())
+	{
+		// 日志级别
+		adminGroup.GET("/log-level", controller.Admin.GetLogLevel)
+		// 修改日志级别
+		adminGroup.PUT("/log-level", controller.Admin.PutLogLevel)
+		// 重新加载配置
+		adminGroup.POST("/config/reload", controller.Admin.PostConfigReload)
+		// 审计日志
+		adminGroup.GET("/audit-logs", controller.Admin.GetAuditLogs)
+		// 导入用户
+		adminGroup.POST("/user-imports", controller.Admin.PostUserImports)
+		// 导入进度
+		adminGroup.GET("/user-imports/:import_id", controller.Admin.GetUserImportsByID)
+		// 下载导入失败的行
+		adminGroup.GET("/user-imports/:import_id/errors", controller.Admin.GetUserImportErrors)
+	}
+}
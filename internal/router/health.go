@@ -0,0 +1,18 @@
+// Package router API 路由
+package router
+
+import (
+	"go-demo/config/di"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Health 健康检查
+//
+//	需要在 r.Use() 之前注册, 不经过限流与超时控制等中间件, 避免高负载时探针失败导致实例被摘除或重启.
+func Health(r *gin.Engine) {
+	// 存活
+	r.GET("/healthz", gin.WrapF(di.Health().LivenessHandler()))
+	// 就绪
+	r.GET("/readyz", gin.WrapF(di.Health().ReadinessHandler()))
+}
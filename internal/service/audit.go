@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+
+	"go-demo/config/di"
+	"go-demo/pkg/gormx"
+)
+
+type audit struct{}
+
+var Audit audit
+
+// Record 记录审计日志
+//
+//	通过 GORM Model 的增删改由 gormx 审计插件自动记录, 原生 SQL 修改或登录等非数据修改的操作使用此方法记录.
+//	操作人与请求信息从 ctx 中获取, 异步写入 t_audit_logs.
+func (audit) Record(ctx context.Context, action, table, primaryKey string, changes map[string]gormx.Change) error {
+	return di.Audit(ctx, []gormx.AuditRecord{gormx.NewAuditRecord(ctx, action, table, primaryKey, changes)})
+}
@@ -0,0 +1,40 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"go-demo/config/di"
+	"go-demo/internal/service"
+	"go-demo/internal/testutil"
+	"go-demo/pkg/gormx"
+)
+
+func TestAuditRecord(t *testing.T) {
+	env := testutil.Setup(t)
+	ctx := gormx.WithActor(context.Background(), gormx.Actor{Type: gormx.ActorAdmin, ID: 7})
+	changes := map[string]gormx.Change{"is_vip": {Before: 0, After: 1}}
+	if err := service.Audit.Record(ctx, gormx.AuditUpdate, "t_users", "1", changes); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := env.Queue.Tasks(di.AuditTask.Name)
+	if len(tasks) != 1 {
+		t.Fatalf("任务数 = %d, want 1", len(tasks))
+	}
+	if tasks[0].Queue != di.AuditTask.Queue {
+		t.Errorf("queue = %s, want %s", tasks[0].Queue, di.AuditTask.Queue)
+	}
+	payload, err := di.AuditTask.Decode(tasks[0].Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Records) != 1 {
+		t.Fatalf("记录数 = %d, want 1", len(payload.Records))
+	}
+	record := payload.Records[0]
+	if record.ActorType != gormx.ActorAdmin || record.ActorID != 7 || record.Action != gormx.AuditUpdate ||
+		record.Table != "t_users" || record.PrimaryKey != "1" {
+		t.Errorf("record = %+v", record)
+	}
+}
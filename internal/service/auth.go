@@ -6,6 +6,8 @@ package service
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"go-demo/config"
@@ -58,6 +60,45 @@ func (auth) JWTLogin(userType string, id int64, userName string) (string, error)
 	return tokenString, nil
 }
 
+// JWTVerify JWT 校验
+//
+//	校验 token 的签名, 有效期与 redis 白名单, 返回登录的用户 id, 无效时返回 0.
+//	userType 为 JWT 登录用户类型, 集中在 consts/auth.go 中定义. token 为 JWT token.
+func (auth) JWTVerify(ctx context.Context, userType, token string) int64 {
+	jwtToken, err := jwt.Parse(token, func(token *jwt.Token) (any, error) {
+		return []byte(config.GetString("jwt_secret")), nil
+	})
+	if err != nil { // token 无效
+		return 0
+	}
+	claims, ok := jwtToken.Claims.(jwt.MapClaims)
+	if !ok || !jwtToken.Valid { // token 秘钥/时间等校验未通过
+		return 0
+	}
+	// 白名单校验
+	key := fmt.Sprintf(consts.JWTLogin, userType, claims["jti"], gox.MD5(token))
+	if n, err := di.JWTRedis().Exists(ctx, key).Result(); err != nil {
+		di.Logger().Error(err.Error())
+		return 0
+	} else if n == 0 { // 不在白名单内
+		return 0
+	}
+
+	return cast.ToInt64(claims["jti"])
+}
+
+// AdminRequest 请求头 Authorization: Bearer <token> 为有效的管理员登录
+//
+//	用于 Gin 之外的 HTTP 服务, 比如诊断服务 di.ServeDiag.
+func (auth) AdminRequest(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return Auth.JWTVerify(r.Context(), consts.AdminJWT, token) > 0
+}
+
 // JWTLogout JWT 登出
 //
 //	从 redis 白名单删除.
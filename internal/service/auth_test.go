@@ -0,0 +1,37 @@
+package service_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-demo/internal/consts"
+	"go-demo/internal/service"
+	"go-demo/internal/testutil"
+)
+
+func TestAdminRequest(t *testing.T) {
+	testutil.Setup(t)
+	admin := testutil.Login(t, consts.AdminJWT, 1, "admin")
+	user := testutil.Login(t, consts.UserJWT, 1, "demo")
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          bool
+	}{
+		{name: "管理员", authorization: admin, want: true},
+		{name: "用户", authorization: user, want: false},
+		{name: "无效 token", authorization: "Bearer x", want: false},
+		{name: "未登录", authorization: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/debug/stats", nil)
+			r.Header.Set("Authorization", tt.authorization)
+			if got := service.Auth.AdminRequest(r); got != tt.want {
+				t.Errorf("AdminRequest = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-demo/config/di"
+	"go-demo/internal/model"
+	"go-demo/internal/types"
+	"go-demo/pkg/ginx"
+	"go-demo/pkg/gormx"
+	"go-demo/pkg/gox"
+	"go-demo/pkg/outbox"
+	"go-demo/pkg/queuex"
+
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+// 导入状态
+const (
+	UserImportProcessing = "processing" // 处理中
+	UserImportDone       = "done"       // 已完成
+)
+
+const (
+	userImportMaxRows   = 10000 // 一个文件最多导入的行数, 不包括表头
+	userImportBatchSize = 500   // 每个任务处理的行数
+)
+
+// ErrUserImportFile 导入文件格式错误, 比如不是 CSV/XLSX, 缺少必需的列, 行数超出限制
+var ErrUserImportFile = errors.New("导入文件不正确")
+
+// userImportPatterns 导入文件的列, 模式同 ginx.GetJSONBody, 表头可以是列名或中文名称
+var userImportPatterns = []string{
+	"user_name:用户名:string:+",
+	"password:密码:string:?",
+	"is_vip:VIP身份:[0,1]:?",
+}
+
+// 用户导入
+type userImport struct{}
+
+var UserImport userImport
+
+// Create 创建导入任务
+//
+//	解析 CSV 或 XLSX 文件, 第一行为表头, 空行忽略. 逐行校验后校验失败的行写入 t_user_import_errors,
+//	其余每 500 行一个任务, 与导入任务在同一事务中写入发件箱, 由 demo-queue 投递并写入, 进度见 Get.
+//	文件格式错误返回 ErrUserImportFile 的包裹.
+func (userImport) Create(ctx context.Context, fileName string, r io.Reader) (*model.TUserImports, error) {
+	table, err := gox.ReadTable(fileName, r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUserImportFile, err)
+	}
+	rows, failures, err := parseUserImport(table)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &model.TUserImports{
+		FileName:      filepath.Base(fileName),
+		Status:        UserImportProcessing,
+		TotalRows:     int64(len(rows) + len(failures)),
+		ProcessedRows: int64(len(failures)),
+		FailedRows:    int64(len(failures)),
+	}
+	if len(rows) == 0 {
+		job.Status = UserImportDone
+	}
+	err = gormx.Transaction(ctx, di.DemoDB(), func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		if len(failures) > 0 {
+			for i := range failures {
+				failures[i].ImportID = job.ID
+			}
+			if err := tx.CreateInBatches(&failures, userImportBatchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		// 任务写入发件箱, 与导入任务一起提交, 由 demo-queue 投递
+		for i, batch := range lo.Chunk(rows, userImportBatchSize) {
+			payload := types.UserImportPayload{ImportID: job.ID, Rows: batch}
+			taskID := queuex.TaskID(fmt.Sprintf("user-import:%d:%d", job.ID, i)) // 同一批只发送一次
+			if err := outbox.EnqueueTask(tx, types.UserImportTask, payload, taskID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, err
+	}
+
+	return UserImport.Get(ctx, job.ID)
+}
+
+// Get 导入任务与进度, 不存在时 ID 为 0
+func (userImport) Get(ctx context.Context, importID int64) (*model.TUserImports, error) {
+	job := &model.TUserImports{}
+	if err := di.DemoDB().WithContext(ctx).Clauses(dbresolver.Write).Where("id = ?", importID).Find(job).Error; err != nil {
+		zap.L().Error(err.Error())
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Process 写入一批行, 由 task.User.Import 调用
+//
+//	批次标记, 用户, 失败的行与进度在一个事务中写入, 失败回滚后重试时整批重新处理; 已提交的批次再次处理 (重复投递或提交后重试) 时跳过.
+//	批次以第一行的行号标识, 见 t_user_import_batches. 用户名已存在的行 (唯一键冲突) 记为失败.
+//	导入任务不存在时返回 gorm.ErrRecordNotFound.
+func (userImport) Process(ctx context.Context, importID int64, rows []types.UserImportRow) error {
+	var job model.TUserImports
+	if err := di.DemoDB().WithContext(ctx).Clauses(dbresolver.Write).Take(&job, importID).Error; err != nil {
+		return err
+	}
+	if job.CreatedBy > 0 { // 接口导入的操作人为管理员
+		ctx = gormx.WithActor(ctx, gormx.Actor{Type: gormx.ActorAdmin, ID: job.CreatedBy})
+	}
+
+	return gormx.Transaction(ctx, di.DemoDB(), func(tx *gorm.DB) error {
+		// 先记录批次, 已处理过的批次跳过. 同一批并发处理时等待唯一键锁, 先提交的生效
+		marker := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.TUserImportBatches{ImportID: importID, RowNo: rows[0].RowNo})
+		if marker.Error != nil {
+			return marker.Error
+		}
+		if marker.RowsAffected == 0 {
+			zap.L().Warn(fmt.Sprintf("用户导入 %d 第 %d 行开始的批次已处理, 跳过", importID, rows[0].RowNo))
+			return nil
+		}
+
+		users := lo.Map(rows, func(row types.UserImportRow, _ int) model.TUsers {
+			return model.TUsers{UserName: row.UserName, Password: row.Password, IsVip: row.IsVip}
+		})
+		// 用户名唯一键冲突时不写入, 已存在的行记为失败
+		result, err := gormx.BatchUpsert(tx.Statement.Context, tx, users, gormx.UpsertOptions{
+			BatchSize:       userImportBatchSize,
+			ConflictColumns: []string{model.TUsersColumns.UserName},
+		})
+		if err != nil {
+			return err
+		}
+		failures := make([]model.TUserImportErrors, 0)
+		for _, i := range result.Existed {
+			failures = append(failures, userImportFailure(importID, rows[i], "用户名已存在"))
+		}
+		for _, f := range result.Failures {
+			zap.L().Error(fmt.Sprintf("用户导入 %d 第 %d 行写入失败: %s", importID, rows[f.Index].RowNo, f.Err.Error()))
+			failures = append(failures, userImportFailure(importID, rows[f.Index], "写入失败"))
+		}
+
+		if len(failures) > 0 {
+			if err := tx.Create(&failures).Error; err != nil {
+				return err
+			}
+		}
+		return userImportProgress(tx, importID, len(rows)-len(failures), len(failures))
+	})
+}
+
+// WriteErrors 以 CSV 格式写入失败的行
+//
+//	表头为行号, 导入列的中文名称与失败原因, 密码不保存, 为空. 修改后可直接重新导入, 行号与失败原因列会被忽略.
+func (userImport) WriteErrors(ctx context.Context, importID int64, w io.Writer) error {
+	var failures []model.TUserImportErrors
+	if err := di.DemoDB().WithContext(ctx).Where("import_id = ?", importID).Order("row_no").Find(&failures).Error; err != nil {
+		zap.L().Error(err.Error())
+		return err
+	}
+
+	header := []string{"行号"}
+	for _, pattern := range userImportPatterns {
+		header = append(header, strings.Split(pattern, ":")[1])
+	}
+	data := [][]string{append(header, "失败原因")}
+	for _, failure := range failures {
+		values := map[string]string{}
+		_ = json.Unmarshal([]byte(failure.Data), &values)
+		line := []string{cast.ToString(failure.RowNo)}
+		for _, pattern := range userImportPatterns {
+			line = append(line, values[strings.Split(pattern, ":")[0]])
+		}
+		data = append(data, append(line, failure.Reason))
+	}
+
+	return gox.WriteCSV(w, data)
+}
+
+// userImportFailure 失败的行, 不包含密码
+func userImportFailure(importID int64, row types.UserImportRow, reason string) model.TUserImportErrors {
+	data, _ := json.Marshal(map[string]string{"user_name": row.UserName, "is_vip": cast.ToString(row.IsVip)})
+	return model.TUserImportErrors{ImportID: importID, RowNo: row.RowNo, Data: string(data), Reason: reason}
+}
+
+// userImportProgress 累加进度, 全部行处理完后状态改为已完成
+//
+//	status 在 processed_rows 之前赋值, MySQL 与 SQLite 中读取的都是修改前的 processed_rows.
+func userImportProgress(tx *gorm.DB, importID int64, success, failed int) error {
+	processed := success + failed
+	return tx.Exec(`UPDATE t_user_imports SET status = CASE WHEN processed_rows + ? >= total_rows THEN ? ELSE status END,
+		processed_rows = processed_rows + ?, success_rows = success_rows + ?, failed_rows = failed_rows + ?, updated_at = ? WHERE id = ?`,
+		processed, UserImportDone, processed, success, failed, time.Now(), importID).Error
+}
+
+// parseUserImport 按表头解析并校验每一行, 返回校验通过的行与失败的行
+func parseUserImport(table [][]string) ([]types.UserImportRow, []model.TUserImportErrors, error) {
+	if len(table) == 0 {
+		return nil, nil, fmt.Errorf("%w: 文件为空", ErrUserImportFile)
+	}
+
+	// 表头, 列下标对应的列名
+	keys := map[int]string{}
+	for i, cell := range table[0] {
+		cell = strings.TrimSpace(cell)
+		for _, pattern := range userImportPatterns {
+			atoms := strings.Split(pattern, ":")
+			if cell == atoms[0] || cell == atoms[1] {
+				keys[i] = atoms[0]
+			}
+		}
+	}
+	if !lo.Contains(lo.Values(keys), "user_name") {
+		return nil, nil, fmt.Errorf("%w: 缺少列 user_name 或 用户名", ErrUserImportFile)
+	}
+
+	rows := make([]types.UserImportRow, 0)
+	failures := make([]model.TUserImportErrors, 0)
+	seen := map[string]int64{} // 用户名第一次出现的行号
+	for i, cells := range table[1:] {
+		rowNo := int64(i + 2)
+		if lo.EveryBy(cells, func(cell string) bool { return strings.TrimSpace(cell) == "" }) {
+			continue
+		}
+		if len(rows)+len(failures) >= userImportMaxRows {
+			return nil, nil, fmt.Errorf("%w: 最多导入 %d 行", ErrUserImportFile, userImportMaxRows)
+		}
+
+		// 空单元格视为没有传值
+		data := map[string]any{}
+		raw := map[string]string{}
+		for j, cell := range cells {
+			key, ok := keys[j]
+			if !ok || strings.TrimSpace(cell) == "" {
+				continue
+			}
+			data[key] = cell
+			if key != "password" {
+				raw[key] = cell
+			}
+		}
+
+		reason := ""
+		values, err := ginx.CheckFields(data, userImportPatterns)
+		var paramErr *ginx.ParamError
+		switch {
+		case errors.As(err, &paramErr) && paramErr.Status == 400:
+			reason = paramErr.Message
+		case err != nil:
+			return nil, nil, err
+		case utf8.RuneCountInString(values["user_name"].(string)) > 50:
+			reason = "用户名不能超过 50 个字符"
+		case seen[values["user_name"].(string)] > 0:
+			reason = fmt.Sprintf("用户名与第 %d 行重复", seen[values["user_name"].(string)])
+		}
+		if reason != "" {
+			data, _ := json.Marshal(raw)
+			failures = append(failures, model.TUserImportErrors{RowNo: rowNo, Data: string(data), Reason: reason})
+			continue
+		}
+
+		row := types.UserImportRow{RowNo: rowNo, UserName: values["user_name"].(string), IsVip: cast.ToInt64(values["is_vip"])}
+		if password, ok := values["password"]; ok {
+			row.Password = gox.PasswordHash(password.(string))
+		}
+		seen[row.UserName] = rowNo
+		rows = append(rows, row)
+	}
+
+	return rows, failures, nil
+}
@@ -4,8 +4,11 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"go-demo/config/di"
 	"go-demo/internal/types"
@@ -18,6 +21,19 @@ type ws struct{}
 
 var WS ws
 
+// 在线的 client, 优雅停止时逐个通知关闭
+var (
+	wsClients   = map[*types.WSClient]struct{}{}
+	wsClientsMu sync.Mutex
+)
+
+// Add 记录在线 client
+func (ws) Add(client *types.WSClient) {
+	wsClientsMu.Lock()
+	defer wsClientsMu.Unlock()
+	wsClients[client] = struct{}{}
+}
+
 // Send 发送消息
 func (ws) Send(client *types.WSClient, msgType string, msgData map[string]any) error {
 	if client.IsClosed {
@@ -46,6 +62,10 @@ func (ws) Send(client *types.WSClient, msgType string, msgData map[string]any) e
 
 // Close 关闭 client
 func (ws) Close(client *types.WSClient) {
+	wsClientsMu.Lock()
+	delete(wsClients, client)
+	wsClientsMu.Unlock()
+
 	if client.IsClosed {
 		return
 	}
@@ -54,3 +74,43 @@ func (ws) Close(client *types.WSClient) {
 	}
 	client.IsClosed = true
 }
+
+// Shutdown 关闭所有在线 client
+//
+//	先向客户端发送 CloseGoingAway 关闭帧, 客户端断开后连接处理函数自行退出; ctx 结束时仍在线的连接直接关闭.
+func (ws) Shutdown(ctx context.Context) error {
+	wsClientsMu.Lock()
+	clients := make([]*types.WSClient, 0, len(wsClients))
+	for client := range wsClients {
+		clients = append(clients, client)
+	}
+	wsClientsMu.Unlock()
+
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown")
+	for _, client := range clients {
+		if err := client.Conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second)); err != nil {
+			di.Logger().Warn(err.Error())
+		}
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		wsClientsMu.Lock()
+		n := len(wsClients)
+		wsClientsMu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			wsClientsMu.Lock()
+			for client := range wsClients {
+				_ = client.Conn.Close()
+			}
+			wsClientsMu.Unlock()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package task
+
+import (
+	"context"
+
+	"go-demo/config/di"
+	"go-demo/internal/model"
+
+	"github.com/goccy/go-json"
+)
+
+// 审计日志
+type audit struct{}
+
+var Audit audit
+
+// Write 写入审计记录, 由 di.Audit 发送
+func (audit) Write(ctx context.Context, payload di.AuditPayload) error {
+	if len(payload.Records) == 0 {
+		return nil
+	}
+
+	logs := make([]model.TAuditLogs, 0, len(payload.Records))
+	for _, record := range payload.Records {
+		changes, err := json.Marshal(record.Changes)
+		if err != nil {
+			return err
+		}
+		logs = append(logs, model.TAuditLogs{
+			ActorType:   record.ActorType,
+			ActorID:     record.ActorID,
+			Action:      record.Action,
+			TargetTable: record.Table,
+			TargetID:    record.PrimaryKey,
+			Changes:     string(changes),
+			IP:          record.IP,
+			RequestID:   record.RequestID,
+			CreatedAt:   record.CreatedAt,
+		})
+	}
+
+	return di.DemoDB().WithContext(ctx).Create(&logs).Error
+}
@@ -3,14 +3,17 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"go-demo/config/di"
 	"go-demo/internal/model"
-	"go-demo/pkg/gox"
-	"go-demo/pkg/queuex"
+	"go-demo/internal/service"
+	"go-demo/internal/types"
+	"go-demo/pkg/gormx"
 
 	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
 )
 
 // 用户相关消息队列 DEMO
@@ -18,28 +21,26 @@ type user struct{}
 
 var User user
 
-func (user) AddUser(ctx context.Context, t *asynq.Task) error {
-	// 解析 payload
-	var user struct {
-		UserName string `json:"user_name"`
-	}
-	if err := queuex.Payload(t, &user); err != nil {
+// AddUser 创建用户, payload 已由 types.UserAddTask 解析并校验
+func (user) AddUser(ctx context.Context, payload types.UserAddPayload) error {
+	// 业务处理
+	user := model.TUsers{UserName: payload.UserName}
+	if err := di.DemoDB().WithContext(ctx).Create(&user).Error; err != nil {
+		if gormx.IsDuplicateKey(err) { // 重试同样冲突
+			return fmt.Errorf("用户名 %s 已存在. %w", payload.UserName, asynq.SkipRetry)
+		}
 		return err
 	}
 
-	// 参数校验
-	if user.UserName == "" {
-		return fmt.Errorf("用户名不得为空. %w", asynq.SkipRetry)
-	}
+	return nil
+}
 
-	// 业务处理
-	userData := map[string]any{}
-	if err := gox.CopyViaJSON(user, &userData); err != nil {
-		return err
-	}
-	if err := di.DemoDB().Model(&model.TUsers{}).Create(userData).Error; err != nil {
-		return err
+// Import 用户导入, 一个任务写入一批行, 由 service.UserImport.Create 发送
+func (user) Import(ctx context.Context, payload types.UserImportPayload) error {
+	err := service.UserImport.Process(ctx, payload.ImportID, payload.Rows)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("导入任务 %d 不存在. %w", payload.ImportID, asynq.SkipRetry)
 	}
 
-	return nil
+	return err
 }
@@ -0,0 +1,40 @@
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-demo/internal/task"
+	"go-demo/internal/testutil"
+	"go-demo/internal/types"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestAddUser(t *testing.T) {
+	testutil.NewDemoDB(t)
+
+	tests := []struct {
+		name          string
+		userName      string
+		wantSkipRetry bool
+	}{
+		{name: "创建", userName: "demo"},
+		{name: "用户名已存在不重试", userName: "demo", wantSkipRetry: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := task.User.AddUser(context.Background(), types.UserAddPayload{UserName: tt.userName})
+			if tt.wantSkipRetry {
+				if !errors.Is(err, asynq.SkipRetry) {
+					t.Fatalf("err = %v, want asynq.SkipRetry", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+// Package testutil 测试辅助函数
+//
+//	在 _test.go 中使用, 替换 di 中依赖外部服务的组件, 使 controller, task 等可以不依赖 MySQL 测试.
+package testutil
+
+import (
+	"testing"
+
+	"go-demo/config/di"
+	"go-demo/internal/model"
+	"go-demo/pkg/gormx"
+
+	"gorm.io/gorm"
+)
+
+// NewDemoDB 创建迁移了全部 Model 的 SQLite 内存数据库, 并注入为 di.DemoDB()
+//
+//	测试结束后关闭并恢复. 注入是全局的, 使用此函数的测试不能并行执行.
+func NewDemoDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := gormx.NewMemoryDB(model.Models()...)
+	if err != nil {
+		t.Fatalf("创建 SQLite 内存数据库失败: %v", err)
+	}
+	restore := di.Override(di.Overrides{DemoDB: db})
+	t.Cleanup(func() {
+		restore()
+		_ = gormx.Close(db)
+	})
+
+	return db
+}
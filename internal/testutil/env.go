@@ -0,0 +1,82 @@
+package testutil
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"go-demo/config"
+	"go-demo/config/di"
+	"go-demo/internal/service"
+	"go-demo/pkg/queuex"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Env 测试环境, 见 Setup
+type Env struct {
+	Redis *miniredis.Miniredis // 进程内 redis, 缓存, 存储, JWT 使用配置的库号
+	DB    *gorm.DB             // SQLite 内存数据库, 即 di.DemoDB()
+	Queue *queuex.Recorder     // 记录发送的任务, 即 di.QueueClient()
+}
+
+// testJWTSecret 未提供 SECRET_JWT_SECRET 时使用的 JWT 密钥, 每个测试进程随机生成
+var testJWTSecret = func() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}()
+
+// Setup 创建测试环境, 替换 di 中依赖外部服务的组件
+//
+//	di.CacheRedis, StorageRedis, JWTRedis 替换为 miniredis, QueueClient 替换为 queuex.Recorder, DemoDB 替换为 SQLite 内存数据库.
+//	测试结束后关闭并恢复. 需要设置 RUNTIME_ENV=testing, 否则跳过测试; 替换是全局的, 使用的测试不能并行执行.
+//	未设置环境变量 SECRET_JWT_SECRET 时使用随机密钥, 数据库密码等其他密钥由 CI 通过环境变量提供.
+func Setup(t testing.TB) *Env {
+	t.Helper()
+	if config.RuntimeEnv() == "prod" {
+		t.Skip("测试需要设置环境变量 RUNTIME_ENV=testing, 避免使用生产环境配置")
+	}
+	if _, ok := os.LookupEnv("SECRET_JWT_SECRET"); !ok {
+		t.Setenv("SECRET_JWT_SECRET", testJWTSecret)
+	}
+
+	mr := miniredis.RunT(t)
+	newRedis := func(index int) *redis.Client {
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), DB: index})
+		t.Cleanup(func() {
+			_ = rdb.Close()
+		})
+		return rdb
+	}
+	queue := queuex.NewRecorder()
+	restore := di.Override(di.Overrides{
+		CacheRedis:   newRedis(config.GetInt("redis_index_cache")),
+		StorageRedis: newRedis(config.GetInt("redis_index_storage")),
+		JWTRedis:     newRedis(config.GetInt("redis_index_jwt")),
+		QueueClient:  queue,
+	})
+	t.Cleanup(restore)
+
+	return &Env{
+		Redis: mr,
+		DB:    NewDemoDB(t),
+		Queue: queue,
+	}
+}
+
+// Login 通过 service.Auth.JWTLogin 登录, 返回请求头 Authorization 的值 "Bearer <token>"
+//
+//	userType 见 consts/auth.go, 需要先调用 Setup, 登录白名单写入 miniredis.
+func Login(t testing.TB, userType string, id int64, userName string) string {
+	t.Helper()
+	token, err := service.Auth.JWTLogin(userType, id, userName)
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+
+	return "Bearer " + token
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"errors"
+
+	"go-demo/internal/consts"
+	"go-demo/pkg/queuex"
+)
+
+// 消息队列任务定义, 发送端与 demo-queue 共用
+var (
+	UserAddTask    = queuex.TaskDef[UserAddPayload]{Name: consts.TaskUserAdd}
+	UserImportTask = queuex.TaskDef[UserImportPayload]{Name: consts.TaskUserImport}
+)
+
+// UserAddPayload 创建用户
+type UserAddPayload struct {
+	UserName string `json:"user_name"`
+}
+
+func (p UserAddPayload) Validate() error {
+	if p.UserName == "" {
+		return errors.New("用户名不得为空")
+	}
+	return nil
+}
+
+// UserImportPayload 用户导入的一批行
+type UserImportPayload struct {
+	ImportID int64           `json:"import_id"`
+	Rows     []UserImportRow `json:"rows"`
+}
+
+func (p UserImportPayload) Validate() error {
+	if p.ImportID == 0 || len(p.Rows) == 0 {
+		return errors.New("导入任务ID与行不得为空")
+	}
+	return nil
+}
+
+// UserImportRow 导入文件中校验通过的行
+type UserImportRow struct {
+	RowNo    int64  `json:"row_no"`
+	UserName string `json:"user_name"`
+	Password string `json:"password"` // 散列后的密码, 文件中没有时为空
+	IsVip    int64  `json:"is_vip"`
+}
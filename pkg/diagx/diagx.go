@@ -0,0 +1,252 @@
+// Package diagx 运行时诊断函数
+//
+//	提供 pprof, goroutine 栈, 构建信息, 生效配置与各服务状态, 应使用独立端口并限制访问.
+package diagx
+
+import (
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	runtimepprof "runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+// GitCommit 构建时通过 -ldflags "-X go-demo/pkg/diagx.GitCommit=<commit>" 注入, 未注入时取 go build 记录的 vcs.revision
+var GitCommit string
+
+var startTime = time.Now()
+
+// StatsFunc 状态函数, 返回值会 json 编码输出
+type StatsFunc func() any
+
+// AuthorizeFunc 请求鉴权, 比如校验管理员登录, 返回 true 时允许访问
+type AuthorizeFunc func(r *http.Request) bool
+
+type NewDiagReq struct {
+	AllowIPs []string   // 允许访问的 IP 或 CIDR, 如 127.0.0.1, 10.0.0.0/8, 未配置时为本机
+	Config   func() any // 生效配置, 输出前会屏蔽敏感字段
+}
+
+// Diag 诊断服务
+type Diag struct {
+	allowNets []*net.IPNet
+	config    func() any
+
+	mu    sync.RWMutex
+	stats map[string]StatsFunc
+}
+
+// New 创建诊断服务
+//
+//	访问控制: 来源 IP 在 AllowIPs 内, 或者通过 Handler 的鉴权函数. AllowIPs 未配置时为本机.
+func New(req NewDiagReq) *Diag {
+	d := &Diag{
+		config: req.Config,
+		stats:  map[string]StatsFunc{},
+	}
+	for _, allowIP := range req.AllowIPs {
+		if !strings.Contains(allowIP, "/") {
+			if strings.Contains(allowIP, ":") {
+				allowIP += "/128"
+			} else {
+				allowIP += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(allowIP)
+		if err != nil {
+			zap.L().Error(err.Error())
+			continue
+		}
+		d.allowNets = append(d.allowNets, ipNet)
+	}
+	if len(d.allowNets) == 0 { // 默认本机
+		_, ipv4Loopback, _ := net.ParseCIDR("127.0.0.0/8")
+		_, ipv6Loopback, _ := net.ParseCIDR("::1/128")
+		d.allowNets = append(d.allowNets, ipv4Loopback, ipv6Loopback)
+	}
+
+	return d
+}
+
+// RegisterStats 注册状态函数
+//
+//	name 建议使用 <类型>:<名称> 的格式, 比如 mysql:demo, pool. 同名注册会覆盖.
+func (d *Diag) RegisterStats(name string, f StatsFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stats[name] = f
+}
+
+// Handler 诊断路由
+//
+//	/debug/pprof/        pprof
+//	/debug/goroutines    全部 goroutine 栈
+//	/debug/buildinfo     构建信息
+//	/debug/config        生效配置, 敏感字段已屏蔽
+//	/debug/stats         运行时与各服务状态
+//
+//	来源 IP 不在白名单内时由 authorize 鉴权, authorize 为 nil 时只允许白名单.
+func (d *Diag) Handler(authorize AuthorizeFunc) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/goroutines", d.goroutines)
+	mux.HandleFunc("/debug/buildinfo", d.buildInfo)
+	mux.HandleFunc("/debug/config", d.effectiveConfig)
+	mux.HandleFunc("/debug/stats", d.allStats)
+
+	return d.auth(mux, authorize)
+}
+
+func (d *Diag) auth(next http.Handler, authorize AuthorizeFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.ipAllowed(r) || authorize != nil && authorize(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writeJSON(w, http.StatusForbidden, map[string]string{"code": "Forbidden", "message": "禁止访问"})
+	})
+}
+
+func (d *Diag) ipAllowed(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range d.allowNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *Diag) goroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := runtimepprof.Lookup("goroutine").WriteTo(w, 2); err != nil {
+		zap.L().Error(err.Error())
+	}
+}
+
+func (d *Diag) buildInfo(w http.ResponseWriter, r *http.Request) {
+	info := map[string]any{
+		"go_version": runtime.Version(),
+		"git_commit": GitCommit,
+		"start_time": startTime.Format(time.DateTime),
+		"uptime":     time.Since(startTime).Round(time.Second).String(),
+	}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		info["path"] = buildInfo.Path
+		settings := map[string]string{}
+		for _, setting := range buildInfo.Settings {
+			settings[setting.Key] = setting.Value
+		}
+		if GitCommit == "" {
+			info["git_commit"] = settings["vcs.revision"]
+		}
+		info["git_time"] = settings["vcs.time"]
+		info["git_modified"] = settings["vcs.modified"]
+		info["settings"] = settings
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (d *Diag) effectiveConfig(w http.ResponseWriter, r *http.Request) {
+	if d.config == nil {
+		writeJSON(w, http.StatusOK, map[string]any{})
+		return
+	}
+	writeJSON(w, http.StatusOK, Mask(d.config()))
+}
+
+func (d *Diag) allStats(w http.ResponseWriter, r *http.Request) {
+	d.mu.RLock()
+	names := make([]string, 0, len(d.stats))
+	for name := range d.stats {
+		names = append(names, name)
+	}
+	d.mu.RUnlock()
+	sort.Strings(names)
+
+	memStats := runtime.MemStats{}
+	runtime.ReadMemStats(&memStats)
+	result := map[string]any{
+		"runtime": map[string]any{
+			"goroutines":     runtime.NumGoroutine(),
+			"gomaxprocs":     runtime.GOMAXPROCS(0),
+			"heap_alloc":     memStats.HeapAlloc,
+			"heap_inuse":     memStats.HeapInuse,
+			"heap_objects":   memStats.HeapObjects,
+			"sys":            memStats.Sys,
+			"num_gc":         memStats.NumGC,
+			"pause_total_ns": memStats.PauseTotalNs,
+		},
+	}
+	for _, name := range names {
+		d.mu.RLock()
+		f := d.stats[name]
+		d.mu.RUnlock()
+		result[name] = f()
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// 敏感字段关键字, 键名包含其中之一即屏蔽
+var sensitiveKeywords = []string{"password", "secret", "auth", "token", "key", "dsn"}
+
+// Mask 屏蔽配置中的敏感字段
+//
+//	支持多级 map, 非 map 数据原样返回.
+func Mask(config any) any {
+	m, ok := config.(map[string]any)
+	if !ok {
+		return config
+	}
+	masked := make(map[string]any, len(m))
+	for k, v := range m {
+		if _, isMap := v.(map[string]any); isMap {
+			masked[k] = Mask(v)
+			continue
+		}
+		masked[k] = v
+		lowerKey := strings.ToLower(k)
+		for _, keyword := range sensitiveKeywords {
+			if strings.Contains(lowerKey, keyword) {
+				masked[k] = "******"
+				break
+			}
+		}
+	}
+
+	return masked
+}
+
+func writeJSON(w http.ResponseWriter, httpCode int, body any) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		zap.L().Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(httpCode)
+	if _, err := w.Write(bodyBytes); err != nil {
+		zap.L().Error(err.Error())
+	}
+}
@@ -0,0 +1,47 @@
+package diagx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-demo/pkg/diagx"
+)
+
+func TestHandlerAuth(t *testing.T) {
+	authorize := func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer admin"
+	}
+
+	tests := []struct {
+		name          string
+		allowIPs      []string
+		authorize     diagx.AuthorizeFunc
+		remoteAddr    string
+		authorization string
+		wantCode      int
+	}{
+		{name: "默认允许本机", remoteAddr: "127.0.0.1:1234", authorize: authorize, wantCode: 200},
+		{name: "默认禁止其他 IP", remoteAddr: "10.0.0.1:1234", authorize: authorize, wantCode: 403},
+		{name: "白名单 CIDR", allowIPs: []string{"10.0.0.0/8"}, remoteAddr: "10.1.2.3:1234", wantCode: 200},
+		{name: "配置白名单后不再默认允许本机", allowIPs: []string{"10.0.0.1"}, remoteAddr: "127.0.0.1:1234", wantCode: 403},
+		{name: "管理员鉴权", remoteAddr: "10.0.0.1:1234", authorize: authorize, authorization: "Bearer admin", wantCode: 200},
+		{name: "鉴权失败", remoteAddr: "10.0.0.1:1234", authorize: authorize, authorization: "Bearer user", wantCode: 403},
+		{name: "没有鉴权函数", remoteAddr: "10.0.0.1:1234", authorization: "Bearer admin", wantCode: 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := diagx.New(diagx.NewDiagReq{AllowIPs: tt.allowIPs}).Handler(tt.authorize)
+			req := httptest.NewRequest(http.MethodGet, "/debug/buildinfo", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d, body = %s", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+// Package genx 代码生成函数
+package genx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Table 表结构
+type Table struct {
+	Name    string
+	Comment string
+	Columns []Column
+}
+
+// Column 列结构
+type Column struct {
+	Name       string
+	Type       string  // 列类型, 比如 varchar(50), decimal(10,2), tinyint(1)
+	Nullable   bool    // 允许 NULL
+	Default    *string // 默认值, 没有默认值为 nil
+	PrimaryKey bool
+	Unique     bool // 单列唯一键
+	Comment    string
+}
+
+// ReadTable 从 INFORMATION_SCHEMA 读取当前库中表的结构, 仅支持 MySQL
+func ReadTable(ctx context.Context, db *gorm.DB, name string) (Table, error) {
+	table := Table{Name: name}
+	tableInfo := struct {
+		TableComment string
+	}{}
+	result := db.WithContext(ctx).Raw("SELECT TABLE_COMMENT AS table_comment FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", name).Scan(&tableInfo)
+	if result.Error != nil {
+		return table, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return table, fmt.Errorf("表 %s 不存在", name)
+	}
+	table.Comment = tableInfo.TableComment
+
+	columns := make([]struct {
+		ColumnName    string
+		ColumnType    string
+		IsNullable    string
+		ColumnDefault *string
+		ColumnKey     string
+		ColumnComment string
+	}, 0)
+	if err := db.WithContext(ctx).Raw(`SELECT COLUMN_NAME AS column_name, COLUMN_TYPE AS column_type, IS_NULLABLE AS is_nullable,
+       COLUMN_DEFAULT AS column_default, COLUMN_KEY AS column_key, COLUMN_COMMENT AS column_comment
+FROM INFORMATION_SCHEMA.COLUMNS
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+ORDER BY ORDINAL_POSITION`, name).Scan(&columns).Error; err != nil {
+		return table, err
+	}
+	for _, c := range columns {
+		table.Columns = append(table.Columns, Column{
+			Name:       c.ColumnName,
+			Type:       c.ColumnType,
+			Nullable:   c.IsNullable == "YES",
+			Default:    c.ColumnDefault,
+			PrimaryKey: c.ColumnKey == "PRI",
+			Unique:     c.ColumnKey == "UNI",
+			Comment:    c.ColumnComment,
+		})
+	}
+
+	return table, nil
+}
+
+// Mixin 可复用的列, 表包含全部列时生成嵌入字段, 不再逐列生成
+type Mixin struct {
+	Type    string   // 嵌入的类型, 比如 gormx.SoftDelete
+	Import  string   // 类型所在的包, 比如 go-demo/pkg/gormx
+	Columns []string // 列名
+}
+
+// GoType 列类型对应的 Go 类型
+//
+//	整数与 tinyint(1) 均为 int64, bigint unsigned 为 uint64, decimal, float, double 为 float64,
+//	date, datetime, timestamp 为 time.Time, 二进制为 []byte, 其他为 string. 允许 NULL 的列不使用指针, NULL 读取为零值.
+func GoType(columnType string) string {
+	t := strings.ToLower(columnType)
+	base, _, _ := strings.Cut(t, "(")
+	base = strings.Fields(base + " ")[0]
+	switch base {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "year":
+		if base == "bigint" && strings.Contains(t, "unsigned") {
+			return "uint64"
+		}
+		return "int64"
+	case "bit", "bool", "boolean":
+		return "int64"
+	case "decimal", "numeric", "float", "double", "real":
+		return "float64"
+	case "date", "datetime", "timestamp":
+		return "time.Time"
+	case "binary", "varbinary", "blob", "tinyblob", "mediumblob", "longblob":
+		return "[]byte"
+	default:
+		return "string"
+	}
+}
+
+// 常见缩写, 字段名中全部大写, 比如 user_id => UserID
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true, "GUID": true,
+	"HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "QPS": true, "RAM": true,
+	"RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true, "TTL": true,
+	"UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true, "URL": true, "UTF8": true, "VM": true,
+	"XML": true, "XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// CamelCase 下划线命名转为 Go 命名, 比如 t_users => TUsers, user_id => UserID
+func CamelCase(name string) string {
+	b := strings.Builder{}
+	for _, word := range strings.Split(name, "_") {
+		if word == "" {
+			continue
+		}
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+
+	return b.String()
+}
+
+// modelDecls 生成的声明, 结构体, TableName() 与列名
+func modelDecls(table Table, mixins []Mixin) string {
+	structName := CamelCase(table.Name)
+	b := bytes.Buffer{}
+
+	// 表包含全部列的 Mixin
+	columnNames := map[string]bool{}
+	for _, c := range table.Columns {
+		columnNames[c.Name] = true
+	}
+	embedded := make([]string, 0)
+	mixinColumns := map[string]bool{}
+	for _, mixin := range mixins {
+		matched := len(mixin.Columns) > 0
+		for _, name := range mixin.Columns {
+			matched = matched && columnNames[name]
+		}
+		if matched {
+			embedded = append(embedded, mixin.Type)
+			for _, name := range mixin.Columns {
+				mixinColumns[name] = true
+			}
+		}
+	}
+
+	// 结构体
+	comment := table.Comment
+	if comment == "" {
+		comment = table.Name
+	}
+	fmt.Fprintf(&b, "// %s %s\n", structName, comment)
+	fmt.Fprintf(&b, "type %s struct {\n", structName)
+	for _, c := range table.Columns {
+		if mixinColumns[c.Name] {
+			continue
+		}
+		tags := make([]string, 0, 5)
+		if c.PrimaryKey {
+			tags = append(tags, "primaryKey")
+		}
+		tags = append(tags, "column:"+c.Name, "type:"+c.Type)
+		if !c.Nullable {
+			tags = append(tags, "not null")
+		}
+		if c.Unique {
+			tags = append(tags, "unique")
+		}
+		if c.Default != nil {
+			if GoType(c.Type) == "string" {
+				tags = append(tags, "default:'"+strings.ReplaceAll(*c.Default, "'", "''")+"'")
+			} else {
+				tags = append(tags, "default:"+*c.Default)
+			}
+		}
+		fmt.Fprintf(&b, "%s %s `gorm:\"%s\" json:\"%s\"`", CamelCase(c.Name), GoType(c.Type), strings.Join(tags, ";"), c.Name)
+		if c.Comment != "" {
+			fmt.Fprintf(&b, " // %s", strings.ReplaceAll(c.Comment, "\n", " "))
+		}
+		b.WriteString("\n")
+	}
+	for _, t := range embedded {
+		b.WriteString(t + "\n")
+	}
+	b.WriteString("}\n\n")
+
+	// 表名
+	b.WriteString("// TableName get sql table name.获取数据库表名\n")
+	fmt.Fprintf(&b, "func (m *%s) TableName() string {\n\treturn %q\n}\n\n", structName, table.Name)
+
+	// 列名
+	fmt.Fprintf(&b, "// %sColumns get sql column name.获取数据库列名\n", structName)
+	fmt.Fprintf(&b, "var %sColumns = struct {\n", structName)
+	for _, c := range table.Columns {
+		fmt.Fprintf(&b, "%s string\n", CamelCase(c.Name))
+	}
+	b.WriteString("}{\n")
+	for _, c := range table.Columns {
+		fmt.Fprintf(&b, "%s: %q,\n", CamelCase(c.Name), c.Name)
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// WriteModel 生成表的 Model 文件
+//
+//	文件不存在时创建; 已存在时只替换结构体, TableName() 与列名变量, 文件中的其他代码保留, 可以重复执行.
+//	表包含 Mixin 的全部列时嵌入 Mixin, 列名变量中仍包含这些列.
+func WriteModel(path, pkg string, table Table, mixins ...Mixin) error {
+	if len(table.Columns) == 0 {
+		return fmt.Errorf("表 %s 没有列", table.Name)
+	}
+	decls := modelDecls(table, mixins)
+
+	src, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		src = []byte("package " + pkg + "\n\n" + decls)
+	} else if err != nil {
+		return err
+	} else {
+		if src, err = replaceDecls(src, table, decls); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if src, err = fixImports(src, mixins); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("%s 格式化失败: %w", path, err)
+	}
+
+	return os.WriteFile(path, formatted, 0o644)
+}
+
+// replaceDecls 删除文件中已生成的声明, 在第一个已生成声明的位置写入新的声明
+func replaceDecls(src []byte, table Table, decls string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	structName := CamelCase(table.Name)
+
+	type span struct{ start, end int }
+	spans := make([]span, 0, 3)
+	for _, decl := range file.Decls {
+		var doc *ast.CommentGroup
+		generated := false
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			doc = d.Doc
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					generated = generated || s.Name.Name == structName
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						generated = generated || n.Name == structName+"Columns"
+					}
+				}
+			}
+			if generated && len(d.Specs) > 1 {
+				return nil, errors.New("生成的声明不能与其他声明在同一个 type 或 var 块中")
+			}
+		case *ast.FuncDecl:
+			doc = d.Doc
+			generated = d.Name.Name == "TableName" && d.Recv != nil && len(d.Recv.List) == 1 && receiverName(d.Recv.List[0].Type) == structName
+		}
+		if !generated {
+			continue
+		}
+		start := decl.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		spans = append(spans, span{fset.Position(start).Offset, fset.Position(decl.End()).Offset})
+	}
+
+	if len(spans) == 0 { // 追加到文件末尾
+		return append(append(bytes.TrimRight(src, "\n"), "\n\n"...), decls...), nil
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	result := append([]byte{}, src[:spans[0].start]...)
+	result = append(result, decls...)
+	for i, s := range spans {
+		next := len(src)
+		if i+1 < len(spans) {
+			next = spans[i+1].start
+		}
+		result = append(result, src[s.end:next]...)
+	}
+
+	return result, nil
+}
+
+func receiverName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+
+	return ""
+}
+
+// fixImports 按是否使用添加或删除 time 与 Mixin 的包, 其他 import 不处理
+func fixImports(src []byte, mixins []Mixin) ([]byte, error) {
+	src, err := fixImport(src, "time", true)
+	if err != nil {
+		return nil, err
+	}
+	for _, mixin := range mixins {
+		if src, err = fixImport(src, mixin.Import, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return src, nil
+}
+
+func fixImport(src []byte, path string, std bool) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	name := path[strings.LastIndex(path, "/")+1:]
+	used := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == name {
+				used = true
+			}
+		}
+		return !used
+	})
+	quoted := strconv.Quote(path)
+	var importSpec *ast.ImportSpec
+	var importDecl *ast.GenDecl
+	for _, decl := range file.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			for _, spec := range d.Specs {
+				if s := spec.(*ast.ImportSpec); s.Path.Value == quoted {
+					importSpec, importDecl = s, d
+				}
+			}
+		}
+	}
+
+	switch {
+	case used && importSpec == nil:
+		// 添加到已有的 import 块中, 标准库在最前, 其他包另起一组
+		for _, decl := range file.Decls {
+			if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT && d.Lparen.IsValid() {
+				if std {
+					offset := fset.Position(d.Lparen).Offset + 1
+					return append(src[:offset:offset], append([]byte("\n\t"+quoted+"\n"), src[offset:]...)...), nil
+				}
+				offset := fset.Position(d.Rparen).Offset
+				return append(src[:offset:offset], append([]byte("\n\t"+quoted+"\n"), src[offset:]...)...), nil
+			}
+		}
+		offset := fset.Position(file.Name.End()).Offset
+		return append(src[:offset:offset], append([]byte("\n\nimport (\n\t"+quoted+"\n)"), src[offset:]...)...), nil
+	case !used && importSpec != nil:
+		start, end := importSpec.Pos(), importSpec.End()
+		if len(importDecl.Specs) == 1 {
+			start, end = importDecl.Pos(), importDecl.End()
+		}
+		startOffset, endOffset := fset.Position(start).Offset, fset.Position(end).Offset
+		return append(src[:startOffset:startOffset], src[endOffset:]...), nil
+	}
+
+	return src, nil
+}
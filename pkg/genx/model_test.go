@@ -0,0 +1,218 @@
+package genx_test
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-demo/pkg/genx"
+)
+
+func TestGoType(t *testing.T) {
+	tests := []struct {
+		columnType string
+		want       string
+	}{
+		{"int", "int64"},
+		{"int(11) unsigned", "int64"},
+		{"tinyint(1)", "int64"},
+		{"bigint(20)", "int64"},
+		{"bigint unsigned", "uint64"},
+		{"BIGINT(20) UNSIGNED", "uint64"},
+		{"year", "int64"},
+		{"bit(1)", "int64"},
+		{"decimal(10,2)", "float64"},
+		{"double unsigned", "float64"},
+		{"date", "time.Time"},
+		{"datetime(3)", "time.Time"},
+		{"timestamp", "time.Time"},
+		{"varbinary(16)", "[]byte"},
+		{"longblob", "[]byte"},
+		{"varchar(50)", "string"},
+		{"enum('a','b')", "string"},
+		{"json", "string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.columnType, func(t *testing.T) {
+			if got := genx.GoType(tt.columnType); got != tt.want {
+				t.Errorf("GoType(%q) = %s, want %s", tt.columnType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"t_users", "TUsers"},
+		{"user_id", "UserID"},
+		{"uuid", "UUID"},
+		{"api_url", "APIURL"},
+		{"is_vip", "IsVip"},
+		{"_created__at_", "CreatedAt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := genx.CamelCase(tt.name); got != tt.want {
+				t.Errorf("CamelCase(%q) = %s, want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+var mixins = []genx.Mixin{
+	{Type: "gormx.Version", Import: "go-demo/pkg/gormx", Columns: []string{"version"}},
+	{Type: "gormx.SoftDelete", Import: "go-demo/pkg/gormx", Columns: []string{"deleted_at"}},
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestWriteModel(t *testing.T) {
+	table := genx.Table{
+		Name:    "t_orders",
+		Comment: "订单",
+		Columns: []genx.Column{
+			{Name: "order_id", Type: "bigint unsigned", PrimaryKey: true, Comment: "订单ID"},
+			{Name: "order_no", Type: "varchar(32)", Unique: true, Default: strPtr("it's"), Comment: "订单号"},
+			{Name: "amount", Type: "decimal(10,2)", Default: strPtr("0.00")},
+			{Name: "remark", Type: "text", Nullable: true},
+			{Name: "created_at", Type: "datetime"},
+			{Name: "version", Type: "int", Default: strPtr("0")},
+		},
+	}
+	tests := []struct {
+		name    string
+		src     string // 已存在的文件内容, 为空时新建
+		table   genx.Table
+		want    []string
+		notWant []string
+	}{
+		{
+			name:  "新建文件",
+			table: table,
+			want: []string{
+				"package model\n",
+				"\"time\"",
+				"\"go-demo/pkg/gormx\"",
+				"// TOrders 订单\ntype TOrders struct {",
+				"OrderID   uint64    `gorm:\"primaryKey;column:order_id;type:bigint unsigned;not null\" json:\"order_id\"`",
+				"// 订单ID",
+				"`gorm:\"column:order_no;type:varchar(32);not null;unique;default:'it''s'\" json:\"order_no\"`",
+				"Amount    float64   `gorm:\"column:amount;type:decimal(10,2);not null;default:0.00\" json:\"amount\"`",
+				"Remark    string    `gorm:\"column:remark;type:text\" json:\"remark\"`",
+				"\tgormx.Version\n}",
+				"func (m *TOrders) TableName() string {\n\treturn \"t_orders\"\n}",
+				"var TOrdersColumns = struct {",
+				"Version:   \"version\",",
+			},
+			notWant: []string{"Version   int64", "gormx.SoftDelete"},
+		},
+		{
+			name: "保留自定义代码, 删除未使用的 import",
+			src: `package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// TOrders 旧结构
+type TOrders struct {
+	OrderID uint64
+	Paid    time.Time
+}
+
+// TableName get sql table name.获取数据库表名
+func (m *TOrders) TableName() string {
+	return "t_orders"
+}
+
+// String 自定义方法
+func (m *TOrders) String() string {
+	return fmt.Sprint(m.OrderID)
+}
+
+var TOrdersColumns = struct {
+	OrderID string
+}{
+	OrderID: "order_id",
+}
+`,
+			table: genx.Table{Name: "t_orders", Columns: []genx.Column{
+				{Name: "order_id", Type: "bigint unsigned", PrimaryKey: true},
+				{Name: "deleted_at", Type: "bigint"},
+			}},
+			want: []string{
+				"import (\n\t\"fmt\"\n\n\t\"go-demo/pkg/gormx\"\n)",
+				"// TOrders t_orders\ntype TOrders struct {",
+				"\tgormx.SoftDelete\n}",
+				"// String 自定义方法\nfunc (m *TOrders) String() string {",
+				"DeletedAt: \"deleted_at\",",
+			},
+			notWant: []string{"\"time\"", "旧结构", "Paid"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "t_orders.go")
+			if tt.src != "" {
+				if err := os.WriteFile(path, []byte(tt.src), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := genx.WriteModel(path, "model", tt.table, mixins...); err != nil {
+				t.Fatal(err)
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(src)
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("缺少 %q\n%s", s, got)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("不应包含 %q\n%s", s, got)
+				}
+			}
+			if strings.Count(got, "type TOrders struct") != 1 || strings.Count(got, "TableName()") != 1 {
+				t.Errorf("声明重复\n%s", got)
+			}
+
+			// 重复执行结果不变
+			if err := genx.WriteModel(path, "model", tt.table, mixins...); err != nil {
+				t.Fatal(err)
+			}
+			again, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != got {
+				t.Errorf("重复执行结果不同\n%s\n---\n%s", got, again)
+			}
+			if _, err := parser.ParseFile(token.NewFileSet(), path, again, 0); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestWriteModelNoColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t_empty.go")
+	if err := genx.WriteModel(path, "model", genx.Table{Name: "t_empty"}); err == nil {
+		t.Fatal("没有列应返回错误")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("不应创建文件, err = %v", err)
+	}
+}
@@ -30,14 +30,25 @@ func GetJSONBody(c *gin.Context, patterns []string) (map[string]any, error) {
 	jsonBody := make(map[string]any)
 	_ = c.ShouldBindJSON(&jsonBody) // 这里的 error 不要处理, 因为空 body 会报 error
 	// 逐字段校验
+	result, err := CheckFields(jsonBody, patterns)
+	if err != nil {
+		return nil, abortParam(c, err)
+	}
+
+	return result, nil
+}
+
+// CheckFields 按 GetJSONBody 的模式校验数据, 不输出响应
+//
+//	用于校验导入文件的行等非请求数据, 校验失败返回 *ParamError.
+func CheckFields(data map[string]any, patterns []string) (map[string]any, error) {
 	result := make(map[string]any)
 	var err error
 	for _, pattern := range patterns {
 		// pattern
 		patternAtoms := strings.Split(pattern, ":")
 		if len(patternAtoms) != 4 {
-			InternalError(c, errors.New("参数模式错误: "+pattern))
-			return nil, errors.New("ParamPatternError")
+			return nil, &ParamError{Status: 500, Code: "ParamPatternError", Message: "参数模式错误: " + pattern}
 		}
 		required := true
 		allowEmpty := false
@@ -52,17 +63,16 @@ func GetJSONBody(c *gin.Context, patterns []string) (map[string]any, error) {
 			allowEmpty = false
 		}
 		// key
-		paramValue, ok := jsonBody[patternAtoms[0]]
+		paramValue, ok := data[patternAtoms[0]]
 		if !ok || paramValue == nil {
 			if required {
-				Error(c, 400, "ParamEmpty", patternAtoms[1]+"不得为空")
-				return nil, errors.New("ParamEmpty")
+				return nil, &ParamError{Status: 400, Code: "ParamEmpty", Message: patternAtoms[1] + "不得为空"}
 			} else {
 				continue
 			}
 		}
 		// 类型值
-		result[patternAtoms[0]], err = FilterParam(c, patternAtoms[1], paramValue, patternAtoms[2], allowEmpty)
+		result[patternAtoms[0]], err = CheckParam(patternAtoms[1], paramValue, patternAtoms[2], allowEmpty)
 		if err != nil {
 			return nil, err
 		}
@@ -126,9 +136,50 @@ func GetQueries(c *gin.Context, patterns []string) (map[string]any, error) {
 //		[]integer 整型64位数组;
 //		[]string 字符串数组;
 func FilterParam(c *gin.Context, paramName string, paramValue any, paramType string, allowEmpty bool) (any, error) {
+	value, err := CheckParam(paramName, paramValue, paramType, allowEmpty)
+	if err != nil {
+		return nil, abortParam(c, err)
+	}
+
+	return value, nil
+}
+
+// ParamError 参数校验错误
+//
+//	Status 为 400 时是参数值不正确, 500 时是参数模式或类型写错了.
+type ParamError struct {
+	Status  int
+	Code    string // ParamEmpty, ParamInvalid, ParamPatternError, ParamTypeError, ParamTypeUndefined
+	Message string
+}
+
+func (e *ParamError) Error() string {
+	return e.Message
+}
+
+// abortParam 输出参数校验错误的响应, 返回以错误码为内容的 error
+func abortParam(c *gin.Context, err error) error {
+	var paramErr *ParamError
+	if !errors.As(err, &paramErr) {
+		InternalError(c, err)
+		return err
+	}
+	if paramErr.Status == 500 {
+		InternalError(c, errors.New(paramErr.Message))
+	} else {
+		Error(c, paramErr.Status, paramErr.Code, paramErr.Message)
+	}
+
+	return errors.New(paramErr.Code)
+}
+
+// CheckParam 校验参数类型, 与 FilterParam 相同但不输出响应
+//
+//	paramType 见 FilterParam, 校验失败返回 *ParamError.
+func CheckParam(paramName string, paramValue any, paramType string, allowEmpty bool) (any, error) {
 	// 整型64位
 	if paramType == "integer" {
-		valueStr, err := FilterParam(c, paramName, paramValue, "string", allowEmpty) // 先统一转字符串再转整型, 这样小数就不允许输入了
+		valueStr, err := CheckParam(paramName, paramValue, "string", allowEmpty) // 先统一转字符串再转整型, 这样小数就不允许输入了
 		if err != nil {
 			return nil, err
 		}
@@ -137,34 +188,31 @@ func FilterParam(c *gin.Context, paramName string, paramValue any, paramType str
 		}
 		valueInt, err := strconv.ParseInt(cast.ToString(valueStr), 10, 64) // 解决前导0被识别为8进制的问题
 		if err != nil {
-			Error(c, 400, "ParamInvalid", paramName+"不正确")
-			return nil, errors.New("ParamInvalid")
+			return nil, &ParamError{Status: 400, Code: "ParamInvalid", Message: paramName + "不正确"}
 		}
 		return valueInt, nil
 	}
 
 	// 正整型64位
 	if paramType == "+integer" {
-		valueInt, err := FilterParam(c, paramName, paramValue, "integer", allowEmpty)
+		valueInt, err := CheckParam(paramName, paramValue, "integer", allowEmpty)
 		if err != nil {
 			return nil, err
 		}
 		if valueInt.(int64) <= 0 {
-			Error(c, 400, "ParamInvalid", paramName+"不正确")
-			return nil, errors.New("ParamInvalid")
+			return nil, &ParamError{Status: 400, Code: "ParamInvalid", Message: paramName + "不正确"}
 		}
 		return valueInt, nil
 	}
 
 	// 非负整型64位
 	if paramType == "!-integer" {
-		valueInt, err := FilterParam(c, paramName, paramValue, "integer", allowEmpty)
+		valueInt, err := CheckParam(paramName, paramValue, "integer", allowEmpty)
 		if err != nil {
 			return nil, err
 		}
 		if valueInt.(int64) < 0 {
-			Error(c, 400, "ParamInvalid", paramName+"不正确")
-			return nil, errors.New("ParamInvalid")
+			return nil, &ParamError{Status: 400, Code: "ParamInvalid", Message: paramName + "不正确"}
 		}
 		return valueInt, nil
 	}
@@ -173,13 +221,11 @@ func FilterParam(c *gin.Context, paramName string, paramValue any, paramType str
 	if paramType == "string" {
 		valueStr, err := cast.ToStringE(paramValue)
 		if err != nil {
-			Error(c, 400, "ParamInvalid", paramName+"不正确")
-			return nil, errors.New("ParamInvalid")
+			return nil, &ParamError{Status: 400, Code: "ParamInvalid", Message: paramName + "不正确"}
 		}
 		valueStr = strings.TrimSpace(valueStr)
 		if valueStr == "" && !allowEmpty {
-			Error(c, 400, "ParamEmpty", paramName+"不得为空")
-			return nil, errors.New("ParamEmpty")
+			return nil, &ParamError{Status: 400, Code: "ParamEmpty", Message: paramName + "不得为空"}
 		}
 
 		return valueStr, nil
@@ -188,7 +234,7 @@ func FilterParam(c *gin.Context, paramName string, paramValue any, paramType str
 	// 浮点数, float.%d, 数字表示精度(没有后补零), 超过精度四舍五入, 点号同数字可省略, 表示无限制, 返回类型为 float64
 	if lo.Substring(paramType, 0, 5) == "float" {
 		// 值
-		valueStr, err := FilterParam(c, paramName, paramValue, "string", allowEmpty)
+		valueStr, err := CheckParam(paramName, paramValue, "string", allowEmpty)
 		if err != nil {
 			return nil, err
 		}
@@ -199,8 +245,7 @@ func FilterParam(c *gin.Context, paramName string, paramValue any, paramType str
 		// float
 		valueFloat, err := cast.ToFloat64E(valueStr)
 		if err != nil {
-			Error(c, 400, "ParamInvalid", paramName+"不正确")
-			return nil, errors.New("ParamInvalid")
+			return nil, &ParamError{Status: 400, Code: "ParamInvalid", Message: paramName + "不正确"}
 		}
 		// 精度
 		prec := -1
@@ -208,8 +253,7 @@ func FilterParam(c *gin.Context, paramName string, paramValue any, paramType str
 		if precStr != "" {
 			prec, err = cast.ToIntE(precStr)
 			if err != nil {
-				InternalError(c, errors.New("数据类型错误: "+paramName))
-				return nil, errors.New("ParamTypeError")
+				return nil, &ParamError{Status: 500, Code: "ParamTypeError", Message: "数据类型错误: " + paramName}
 			}
 		}
 		if prec == -1 {
@@ -227,11 +271,10 @@ func FilterParam(c *gin.Context, paramName string, paramValue any, paramType str
 			var err error
 			prec, err = cast.ToIntE(precStr)
 			if err != nil {
-				InternalError(c, errors.New("数据类型错误: "+paramName))
-				return nil, errors.New("ParamTypeError")
+				return nil, &ParamError{Status: 500, Code: "ParamTypeError", Message: "数据类型错误: " + paramName}
 			}
 		}
-		valueFloat, err := FilterParam(c, paramName, paramValue, fmt.Sprintf("float.%d", prec), allowEmpty)
+		valueFloat, err := CheckParam(paramName, paramValue, fmt.Sprintf("float.%d", prec), allowEmpty)
 		if err != nil {
 			return nil, err
 		}
@@ -244,8 +287,7 @@ func FilterParam(c *gin.Context, paramName string, paramValue any, paramType str
 		valueType := reflect.TypeOf(paramValue).String() // 用户输入值类型
 		enum := make([]any, 0)
 		if err := json.Unmarshal([]byte(paramType), &enum); err != nil { // 候选值解析到切片
-			Error(c, 400, "ParamInvalid", paramName+"不正确")
-			return nil, errors.New("ParamInvalid")
+			return nil, &ParamError{Status: 400, Code: "ParamInvalid", Message: paramName + "不正确"}
 		}
 		for _, value := range enum { // 用户输入与候选值逐个比较
 			enumType := reflect.TypeOf(value).String() // 候选值类型
@@ -265,19 +307,16 @@ func FilterParam(c *gin.Context, paramName string, paramValue any, paramType str
 			} else if valueType == "string" {
 				valueFloat, err := cast.ToFloat64E(paramValue)
 				if err != nil {
-					Error(c, 400, "ParamInvalid", paramName+"不正确")
-					return nil, errors.New("ParamInvalid")
+					return nil, &ParamError{Status: 400, Code: "ParamInvalid", Message: paramName + "不正确"}
 				}
 				if valueFloat == value {
 					return valueFloat, nil
 				}
 			} else {
-				Error(c, 400, "ParamInvalid", paramName+"不正确")
-				return nil, errors.New("ParamInvalid")
+				return nil, &ParamError{Status: 400, Code: "ParamInvalid", Message: paramName + "不正确"}
 			}
 		}
-		Error(c, 400, "ParamInvalid", paramName+"不正确")
-		return nil, errors.New("ParamInvalid")
+		return nil, &ParamError{Status: 400, Code: "ParamInvalid", Message: paramName + "不正确"}
 	}
 
 	// 数组
@@ -285,24 +324,22 @@ func FilterParam(c *gin.Context, paramName string, paramValue any, paramType str
 		valueType := reflect.TypeOf(paramValue).String() // 用户输入值类型
 		if valueType == "[]interface {}" {
 			if !allowEmpty && len(paramValue.([]any)) == 0 {
-				Error(c, 400, "ParamEmpty", paramName+"不得为空")
-				return nil, errors.New("ParamEmpty")
+				return nil, &ParamError{Status: 400, Code: "ParamEmpty", Message: paramName + "不得为空"}
 			}
 			return paramValue, nil
 		}
-		Error(c, 400, "ParamInvalid", paramName+"不正确")
-		return nil, errors.New("ParamInvalid")
+		return nil, &ParamError{Status: 400, Code: "ParamInvalid", Message: paramName + "不正确"}
 	}
 
 	// int64 数组
 	if paramType == "[]integer" {
-		valueArr, err := FilterParam(c, paramName, paramValue, "array", allowEmpty)
+		valueArr, err := CheckParam(paramName, paramValue, "array", allowEmpty)
 		if err != nil {
 			return nil, err
 		}
 		intSlice := make([]int64, 0)
 		for _, item := range valueArr.([]any) {
-			itemAny, err := FilterParam(c, paramName, item, "integer", false)
+			itemAny, err := CheckParam(paramName, item, "integer", false)
 			if err != nil {
 				return nil, err
 			}
@@ -313,13 +350,13 @@ func FilterParam(c *gin.Context, paramName string, paramValue any, paramType str
 
 	// string 数组
 	if paramType == "[]string" {
-		arrayValue, err := FilterParam(c, paramName, paramValue, "array", allowEmpty)
+		arrayValue, err := CheckParam(paramName, paramValue, "array", allowEmpty)
 		if err != nil {
 			return nil, err
 		}
 		stringSlice := make([]string, 0)
 		for _, item := range arrayValue.([]any) {
-			itemAny, err := FilterParam(c, paramName, item, "string", false)
+			itemAny, err := CheckParam(paramName, item, "string", false)
 			if err != nil {
 				return nil, err
 			}
@@ -328,8 +365,7 @@ func FilterParam(c *gin.Context, paramName string, paramValue any, paramType str
 		return stringSlice, nil
 	}
 
-	InternalError(c, errors.New("未知数据类型: "+paramName))
-	return nil, errors.New("ParamTypeUndefined")
+	return nil, &ParamError{Status: 500, Code: "ParamTypeUndefined", Message: "未知数据类型: " + paramName}
 }
 
 // PageQuery 分页参数
@@ -362,7 +398,7 @@ func Paginate(c *gin.Context, items any, pageQuery PageQuery) (*Paging, error) {
 	page := queries["page"].(int64)
 	perPage := queries["per_page"].(int64)
 
-	tx := pageQuery.DB
+	tx := pageQuery.DB.WithContext(c.Request.Context())
 	if pageQuery.Model != nil {
 		tx = tx.Model(pageQuery.Model)
 	}
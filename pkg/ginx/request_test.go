@@ -0,0 +1,94 @@
+package ginx_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go-demo/pkg/ginx"
+)
+
+func TestCheckFields(t *testing.T) {
+	patterns := []string{"user_name:用户名:string:+", "money:金额:decimal.2:*", "is_vip:VIP身份:[0,1]:?", "version:版本号:!-integer:?"}
+
+	tests := []struct {
+		name     string
+		data     map[string]any
+		patterns []string
+		want     map[string]any
+		wantCode string
+	}{
+		{
+			name: "全部字段",
+			data: map[string]any{"user_name": " demo ", "money": "1.236", "is_vip": "1", "version": float64(3)},
+			want: map[string]any{"user_name": "demo", "money": "1.24", "is_vip": float64(1), "version": int64(3)},
+		},
+		{
+			name: "选传字段缺失, 忽略未声明的字段",
+			data: map[string]any{"user_name": "demo", "password": "111111"},
+			want: map[string]any{"user_name": "demo"},
+		},
+		{
+			name: "* 允许空值",
+			data: map[string]any{"user_name": "demo", "money": ""},
+			want: map[string]any{"user_name": "demo", "money": "0.00"},
+		},
+		{
+			name:     "必传字段缺失",
+			data:     map[string]any{"money": "1"},
+			wantCode: "ParamEmpty",
+		},
+		{
+			name:     "必传字段为 null",
+			data:     map[string]any{"user_name": nil},
+			wantCode: "ParamEmpty",
+		},
+		{
+			name:     "? 不允许空值",
+			data:     map[string]any{"user_name": "demo", "version": ""},
+			wantCode: "ParamEmpty",
+		},
+		{
+			name:     "枚举不匹配",
+			data:     map[string]any{"user_name": "demo", "is_vip": "2"},
+			wantCode: "ParamInvalid",
+		},
+		{
+			name:     "负数",
+			data:     map[string]any{"user_name": "demo", "version": "-1"},
+			wantCode: "ParamInvalid",
+		},
+		{
+			name:     "小数不是整数",
+			data:     map[string]any{"user_name": "demo", "version": "1.5"},
+			wantCode: "ParamInvalid",
+		},
+		{
+			name:     "模式错误",
+			data:     map[string]any{"user_name": "demo"},
+			patterns: []string{"user_name:用户名:string"},
+			wantCode: "ParamPatternError",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.patterns == nil {
+				tt.patterns = patterns
+			}
+			got, err := ginx.CheckFields(tt.data, tt.patterns)
+			if tt.wantCode != "" {
+				var paramErr *ginx.ParamError
+				if !errors.As(err, &paramErr) || paramErr.Code != tt.wantCode {
+					t.Fatalf("err = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
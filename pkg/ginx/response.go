@@ -4,6 +4,10 @@
 package ginx
 
 import (
+	"errors"
+
+	"go-demo/pkg/gormx"
+
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -52,3 +56,14 @@ func InternalError(c *gin.Context, err error) {
 	}
 	Error(c, 500, "InternalError", "服务异常, 请稍后重试")
 }
+
+// DBError 输出数据库操作错误
+//
+//	乐观锁检查失败输出 409 ConcurrentModification, 其他错误输出 500. 数据库错误已由 GORM 日志记录, 无需重复记录.
+func DBError(c *gin.Context, err error) {
+	if errors.Is(err, gormx.ErrConcurrentModification) {
+		Error(c, 409, "ConcurrentModification", "数据已被修改, 请刷新后重试")
+		return
+	}
+	InternalError(c, nil)
+}
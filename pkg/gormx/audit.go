@@ -0,0 +1,436 @@
+package gormx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"gorm.io/plugin/dbresolver"
+)
+
+// 审计操作
+const (
+	AuditCreate = "create" // 创建
+	AuditUpdate = "update" // 修改
+	AuditDelete = "delete" // 删除, 包括软删除
+)
+
+const (
+	auditBeforeKey  = "gormx:audit_before"
+	auditMaskValue  = "***"
+	auditMaxRowsDef = 1000
+)
+
+// RequestInfo 请求信息, 写入审计记录
+type RequestInfo struct {
+	IP        string
+	RequestID string
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo ctx 中写入请求信息
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFrom ctx 中的请求信息, 没有时为空
+func RequestInfoFrom(ctx context.Context) RequestInfo {
+	if ctx == nil {
+		return RequestInfo{}
+	}
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
+
+// Change 字段修改前后的值, 创建时 Before 为 nil, 删除时 After 为 nil
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditRecord 审计记录, 一行数据的一次修改
+type AuditRecord struct {
+	ActorType  string            `json:"actor_type"`  // 操作人类型, 见 Actor
+	ActorID    int64             `json:"actor_id"`    // 操作人 ID
+	Action     string            `json:"action"`      // AuditCreate, AuditUpdate, AuditDelete
+	Table      string            `json:"table"`       // 表名
+	PrimaryKey string            `json:"primary_key"` // 主键, 联合主键以逗号分隔
+	Changes    map[string]Change `json:"changes"`     // 有修改的列
+	IP         string            `json:"ip"`
+	RequestID  string            `json:"request_id"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// NewAuditRecord 使用 ctx 中的操作人与请求信息创建审计记录
+//
+//	GORM 以外的修改, 比如原生 SQL, 调用方自行创建记录并写入.
+func NewAuditRecord(ctx context.Context, action, table, primaryKey string, changes map[string]Change) AuditRecord {
+	actor := ActorFrom(ctx)
+	info := RequestInfoFrom(ctx)
+
+	return AuditRecord{
+		ActorType:  actor.Type,
+		ActorID:    actor.ID,
+		Action:     action,
+		Table:      table,
+		PrimaryKey: primaryKey,
+		Changes:    changes,
+		IP:         info.IP,
+		RequestID:  info.RequestID,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// AuditSink 写入审计记录, 同一条语句的记录一次写入
+type AuditSink func(ctx context.Context, records []AuditRecord) error
+
+// AuditConfig 审计配置
+type AuditConfig struct {
+	Tables      []string  // 审计的表
+	MaskColumns []string  // 脱敏的列, 比如 password, 只记录有修改, 值记录为 ***
+	MaxRows     int       // 一条修改或删除语句最多审计的行数, 默认 1000, 超出的行不记录
+	Sink        AuditSink // 写入审计记录, 通常发送到消息队列异步写入
+}
+
+// auditPlugin 数据修改审计插件
+type auditPlugin struct {
+	tables  map[string]bool
+	mask    map[string]bool
+	maxRows int
+	sink    AuditSink
+}
+
+// NewAuditPlugin GORM 数据修改审计插件
+//
+//	记录 Tables 中的表通过 Model 的增删改, 每行一条记录, 包含有修改的列修改前后的值.
+//	修改与删除前按语句的条件查询修改前的数据, 修改后按主键查询修改后的数据, 均使用主库. 原生 SQL, 未使用 Model 的语句与 BatchUpsert 不记录.
+//	事务中的记录在提交后写入, 回滚时丢弃, 见 Transaction.
+func NewAuditPlugin(cfg AuditConfig) gorm.Plugin {
+	p := &auditPlugin{
+		tables:  map[string]bool{},
+		mask:    map[string]bool{},
+		maxRows: cfg.MaxRows,
+		sink:    cfg.Sink,
+	}
+	for _, table := range cfg.Tables {
+		p.tables[table] = true
+	}
+	for _, column := range cfg.MaskColumns {
+		p.mask[column] = true
+	}
+	if p.maxRows <= 0 {
+		p.maxRows = auditMaxRowsDef
+	}
+
+	return p
+}
+
+func (p *auditPlugin) Name() string {
+	return "gormx:audit"
+}
+
+func (p *auditPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("gormx:audit_after_create", p.afterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("gormx:audit_before_update", p.beforeWrite); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("gormx:audit_after_update", p.afterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("gormx:audit_before_delete", p.beforeWrite); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("gormx:audit_after_delete", p.afterDelete); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// audited 是否需要审计
+func (p *auditPlugin) audited(db *gorm.DB) bool {
+	return db.Error == nil && !db.DryRun && p.sink != nil &&
+		db.Statement.Schema != nil && len(db.Statement.Schema.PrimaryFields) > 0 && p.tables[db.Statement.Table]
+}
+
+// afterCreate 按主键查询创建后的数据
+//
+//	带冲突处理的语句 (INSERT ... ON DUPLICATE KEY UPDATE, INSERT IGNORE 等, 如 BatchUpsert 指定 ConflictColumns 时) 回填的主键不准确,
+//	也无法区分新增与修改, 不记录; 普通 INSERT 包括不指定冲突处理的 BatchUpsert 照常记录.
+func (p *auditPlugin) afterCreate(db *gorm.DB) {
+	if !p.audited(db) || db.RowsAffected == 0 {
+		return
+	}
+	if _, ok := db.Statement.Clauses[clause.OnConflict{}.Name()]; ok {
+		return
+	}
+	pks := p.createdPrimaryKeys(db)
+	if len(pks) == 0 {
+		return
+	}
+	column, values := schema.ToQueryValues(db.Statement.Table, db.Statement.Schema.PrimaryFieldDBNames, pks)
+	after, err := p.snapshot(db, []clause.Expression{clause.IN{Column: column, Values: values}}, true)
+	if err != nil {
+		zap.L().Error("审计创建后数据查询失败: " + err.Error())
+		return
+	}
+
+	records := make([]AuditRecord, 0, len(after))
+	for _, row := range after {
+		records = append(records, p.record(db, AuditCreate, row, p.diff(nil, row)))
+	}
+	p.emit(db, records)
+}
+
+// beforeWrite 按语句的条件查询修改或删除前的数据
+func (p *auditPlugin) beforeWrite(db *gorm.DB) {
+	if !p.audited(db) {
+		return
+	}
+	conds := p.conditions(db)
+	if len(conds) == 0 && !db.AllowGlobalUpdate { // 没有条件的语句 GORM 会拒绝执行
+		return
+	}
+	before, err := p.snapshot(db, conds, db.Statement.Unscoped)
+	if err != nil {
+		zap.L().Error("审计修改前数据查询失败: " + err.Error())
+		return
+	}
+	db.InstanceSet(auditBeforeKey, before)
+}
+
+// afterUpdate 按主键查询修改后的数据, 与修改前对比
+func (p *auditPlugin) afterUpdate(db *gorm.DB) {
+	value, ok := db.InstanceGet(auditBeforeKey)
+	if !ok || db.Error != nil || db.RowsAffected == 0 {
+		return
+	}
+	before := value.([]map[string]any)
+	if len(before) == 0 {
+		return
+	}
+	after, err := p.snapshot(db, []clause.Expression{p.primaryKeyIn(db, before)}, true)
+	if err != nil {
+		zap.L().Error("审计修改后数据查询失败: " + err.Error())
+		return
+	}
+	afterByPK := make(map[string]map[string]any, len(after))
+	for _, row := range after {
+		afterByPK[p.primaryKey(db, row)] = row
+	}
+
+	records := make([]AuditRecord, 0, len(before))
+	for _, row := range before {
+		changes := p.diff(row, afterByPK[p.primaryKey(db, row)])
+		if len(changes) == 0 {
+			continue
+		}
+		records = append(records, p.record(db, AuditUpdate, row, changes))
+	}
+	p.emit(db, records)
+}
+
+// afterDelete 记录删除前的数据
+func (p *auditPlugin) afterDelete(db *gorm.DB) {
+	value, ok := db.InstanceGet(auditBeforeKey)
+	if !ok || db.Error != nil || db.RowsAffected == 0 {
+		return
+	}
+	before := value.([]map[string]any)
+	records := make([]AuditRecord, 0, len(before))
+	for _, row := range before {
+		records = append(records, p.record(db, AuditDelete, row, p.diff(row, nil)))
+	}
+	p.emit(db, records)
+}
+
+// emit 写入审计记录, 事务中提交后写入
+func (p *auditPlugin) emit(db *gorm.DB, records []AuditRecord) {
+	if len(records) == 0 {
+		return
+	}
+	AfterCommit(db, func(ctx context.Context) error {
+		if err := p.sink(ctx, records); err != nil {
+			return fmt.Errorf("审计记录写入失败: %w", err)
+		}
+		return nil
+	})
+}
+
+func (p *auditPlugin) record(db *gorm.DB, action string, row map[string]any, changes map[string]Change) AuditRecord {
+	return NewAuditRecord(db.Statement.Context, action, db.Statement.Table, p.primaryKey(db, row), changes)
+}
+
+// snapshot 在同一连接上查询数据, 事务中读到事务内的修改, 非事务时使用主库
+func (p *auditPlugin) snapshot(db *gorm.DB, conds []clause.Expression, unscoped bool) ([]map[string]any, error) {
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+		Clauses(dbresolver.Write).
+		Model(reflect.New(db.Statement.Schema.ModelType).Interface())
+	if unscoped {
+		tx = tx.Unscoped()
+	}
+	if len(conds) > 0 {
+		tx = tx.Clauses(clause.Where{Exprs: conds})
+	}
+	rows := make([]map[string]any, 0)
+	if err := tx.Limit(p.maxRows + 1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) > p.maxRows {
+		zap.L().Warn(fmt.Sprintf("审计 %s 的语句影响超过 %d 行, 超出的行不记录", db.Statement.Table, p.maxRows))
+		rows = rows[:p.maxRows]
+	}
+
+	return rows, nil
+}
+
+// conditions 语句的条件, 包括 Model 与 Dest 中的主键, 与 GORM 生成修改和删除语句的条件一致
+func (p *auditPlugin) conditions(db *gorm.DB) []clause.Expression {
+	conds := make([]clause.Expression, 0)
+	if c, ok := db.Statement.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			conds = append(conds, where.Exprs...)
+		}
+	}
+	for _, value := range []any{db.Statement.Model, db.Statement.Dest} {
+		if value == nil {
+			continue
+		}
+		_, pks := schema.GetIdentityFieldValuesMap(db.Statement.Context, reflect.ValueOf(value), db.Statement.Schema.PrimaryFields)
+		if len(pks) == 0 {
+			continue
+		}
+		column, values := schema.ToQueryValues(db.Statement.Table, db.Statement.Schema.PrimaryFieldDBNames, pks)
+		conds = append(conds, clause.IN{Column: column, Values: values})
+	}
+
+	return conds
+}
+
+// createdPrimaryKeys 创建的数据的主键, 自增主键由 GORM 回填
+func (p *auditPlugin) createdPrimaryKeys(db *gorm.DB) [][]any {
+	fromMaps := func(maps []map[string]any) [][]any {
+		pks := make([][]any, 0, len(maps))
+		for _, m := range maps {
+			pk := make([]any, 0, len(db.Statement.Schema.PrimaryFieldDBNames))
+			for _, name := range db.Statement.Schema.PrimaryFieldDBNames {
+				if v, ok := m[name]; ok && v != nil {
+					pk = append(pk, v)
+				}
+			}
+			if len(pk) == len(db.Statement.Schema.PrimaryFieldDBNames) {
+				pks = append(pks, pk)
+			}
+		}
+		return pks
+	}
+
+	switch dest := db.Statement.Dest.(type) {
+	case map[string]any:
+		return fromMaps([]map[string]any{dest})
+	case *map[string]any:
+		return fromMaps([]map[string]any{*dest})
+	case []map[string]any:
+		return fromMaps(dest)
+	case *[]map[string]any:
+		return fromMaps(*dest)
+	default:
+		_, pks := schema.GetIdentityFieldValuesMap(db.Statement.Context, db.Statement.ReflectValue, db.Statement.Schema.PrimaryFields)
+		return pks
+	}
+}
+
+// primaryKeyIn 按主键查询的条件
+func (p *auditPlugin) primaryKeyIn(db *gorm.DB, rows []map[string]any) clause.Expression {
+	pks := make([][]any, 0, len(rows))
+	for _, row := range rows {
+		pk := make([]any, 0, len(db.Statement.Schema.PrimaryFieldDBNames))
+		for _, name := range db.Statement.Schema.PrimaryFieldDBNames {
+			pk = append(pk, row[name])
+		}
+		pks = append(pks, pk)
+	}
+	column, values := schema.ToQueryValues(db.Statement.Table, db.Statement.Schema.PrimaryFieldDBNames, pks)
+
+	return clause.IN{Column: column, Values: values}
+}
+
+// primaryKey 主键, 联合主键以逗号分隔
+func (p *auditPlugin) primaryKey(db *gorm.DB, row map[string]any) string {
+	values := make([]string, 0, len(db.Statement.Schema.PrimaryFieldDBNames))
+	for _, name := range db.Statement.Schema.PrimaryFieldDBNames {
+		values = append(values, fmt.Sprint(auditValue(row[name])))
+	}
+
+	return strings.Join(values, ",")
+}
+
+// diff 有修改的列, before 为 nil 时为创建, after 为 nil 时为删除
+func (p *auditPlugin) diff(before, after map[string]any) map[string]Change {
+	changes := map[string]Change{}
+	columns := before
+	if columns == nil {
+		columns = after
+	}
+	for column := range columns {
+		var b, a any
+		if before != nil {
+			b = auditValue(before[column])
+		}
+		if after != nil {
+			a = auditValue(after[column])
+		}
+		if auditEqual(b, a) {
+			continue
+		}
+		if p.mask[column] {
+			if b != nil {
+				b = auditMaskValue
+			}
+			if a != nil {
+				a = auditMaskValue
+			}
+		}
+		changes[column] = Change{Before: b, After: a}
+	}
+
+	return changes
+}
+
+// auditValue 统一查询结果中的值, 指针取值, []byte 转为字符串
+func auditValue(v any) any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	if b, ok := rv.Interface().([]byte); ok {
+		return string(b)
+	}
+
+	return rv.Interface()
+}
+
+func auditEqual(a, b any) bool {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,297 @@
+package gormx
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+)
+
+const (
+	cachePluginName = "gormx:cache"
+	cacheSettingKey = "gormx:cache"
+)
+
+// cacheSetting Cache() 设置的缓存参数
+type cacheSetting struct {
+	ttl    time.Duration
+	tables []string
+}
+
+// Cache 缓存查询结果
+//
+//	按需开启, 比如 db.Scopes(gormx.Cache(time.Minute)).Where("user_id = ?", userID).Find(&user), 仅对 Find, First, Take, Last, Pluck 生效.
+//	通过 GORM 对表的增删改会使该表的缓存失效, 关联查询需要在 tables 中声明其他表, 原生 SQL 写入后需要调用 InvalidateCache().
+//	事务中的查询不使用缓存. 结果使用 msgpack 序列化, 仅支持结构体, 基础类型及其切片.
+func Cache(ttl time.Duration, tables ...string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Set(cacheSettingKey, cacheSetting{ttl: ttl, tables: tables})
+	}
+}
+
+// InvalidateCache 使表的查询缓存失效
+//
+//	原生 SQL 写入后使用, 未启用查询缓存时不做处理.
+func InvalidateCache(ctx context.Context, db *gorm.DB, tables ...string) error {
+	p, ok := db.Config.Plugins[cachePluginName].(*cachePlugin)
+	if !ok {
+		return nil
+	}
+
+	return p.invalidate(ctx, tables)
+}
+
+// cacheEntry 缓存的查询结果
+type cacheEntry struct {
+	RowsAffected int64  `msgpack:"r"`
+	Dest         []byte `msgpack:"d"`
+}
+
+// cachePlugin 查询缓存插件
+//
+//	缓存键为 SQL + 绑定参数 + 结果类型 + 涉及表的版本号, 表有写入时版本号加一, 旧缓存不再命中, 等待过期.
+//	相同缓存键的并发查询只查询一次数据库.
+type cachePlugin struct {
+	rdb    redis.UniversalClient
+	prefix string
+	group  singleflight.Group
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewCachePlugin GORM 查询缓存插件
+//
+//	prefix 为缓存 key 前缀, 多个数据库使用同一 redis 时需要区分.
+func NewCachePlugin(rdb redis.UniversalClient, prefix string) gorm.Plugin {
+	return &cachePlugin{
+		rdb:    rdb,
+		prefix: prefix,
+	}
+}
+
+func (p *cachePlugin) Name() string {
+	return cachePluginName
+}
+
+func (p *cachePlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Replace("gorm:query", p.query); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("gormx:cache_after_create", p.afterWrite); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("gormx:cache_after_update", p.afterWrite); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("gormx:cache_after_delete", p.afterWrite); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// query 替换 gorm:query, 开启缓存的查询先查缓存
+func (p *cachePlugin) query(db *gorm.DB) {
+	value, ok := db.Get(cacheSettingKey)
+	if !ok || db.Error != nil || db.DryRun || !cacheable(db) {
+		callbacks.Query(db)
+		return
+	}
+	setting := value.(cacheSetting)
+
+	callbacks.BuildQuerySQL(db)
+	if db.Error != nil {
+		return
+	}
+	ctx := db.Statement.Context
+	tables := append([]string{db.Statement.Table}, setting.tables...)
+	key, err := p.key(ctx, db, tables)
+	if err != nil {
+		zap.L().Warn("查询缓存不可用: " + err.Error())
+		callbacks.Query(db)
+		return
+	}
+
+	// 缓存
+	data, err := p.rdb.Get(ctx, key).Bytes()
+	if err == nil {
+		if err := p.load(db, data); err == nil {
+			p.hits.Add(1)
+			return
+		}
+		zap.L().Warn("查询缓存解析失败: " + err.Error())
+	} else if !errors.Is(err, redis.Nil) {
+		zap.L().Warn("查询缓存不可用: " + err.Error())
+	}
+	p.misses.Add(1)
+
+	// 数据库, 并发查询只有一个执行
+	leader := false
+	result, err, _ := p.group.Do(key, func() (any, error) {
+		leader = true
+		callbacks.Query(db)
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			return nil, db.Error
+		}
+		dest, err := msgpack.Marshal(db.Statement.Dest)
+		if err != nil {
+			return nil, err
+		}
+		data, err := msgpack.Marshal(cacheEntry{RowsAffected: db.RowsAffected, Dest: dest})
+		if err != nil {
+			return nil, err
+		}
+		if err := p.rdb.Set(ctx, key, data, setting.ttl).Err(); err != nil {
+			zap.L().Warn("查询缓存写入失败: " + err.Error())
+		}
+		return data, nil
+	})
+	if leader {
+		if err != nil && db.Error == nil { // 序列化失败, 不影响查询结果
+			zap.L().Warn("查询缓存序列化失败: " + err.Error())
+		}
+		return
+	}
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	if err := p.load(db, result.([]byte)); err != nil {
+		_ = db.AddError(err)
+	}
+}
+
+// load 使用缓存的查询结果
+func (p *cachePlugin) load(db *gorm.DB, data []byte) error {
+	entry := cacheEntry{}
+	if err := msgpack.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	if entry.RowsAffected > 0 || reflect.Indirect(reflect.ValueOf(db.Statement.Dest)).Kind() == reflect.Slice {
+		if err := msgpack.Unmarshal(entry.Dest, db.Statement.Dest); err != nil {
+			return err
+		}
+	}
+	db.RowsAffected = entry.RowsAffected
+	if db.RowsAffected == 0 && db.Statement.RaiseErrorOnNotFound {
+		_ = db.AddError(gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// key 缓存键, 包含涉及表的当前版本号
+func (p *cachePlugin) key(ctx context.Context, db *gorm.DB, tables []string) (string, error) {
+	versionKeys := make([]string, 0, len(tables))
+	for _, table := range tables {
+		versionKeys = append(versionKeys, p.versionKey(table))
+	}
+	versions, err := p.rdb.MGet(ctx, versionKeys...).Result()
+	if err != nil {
+		return "", err
+	}
+
+	vars := make([]string, 0, len(db.Statement.Vars))
+	for _, v := range db.Statement.Vars {
+		if valuer, ok := v.(driver.Valuer); ok {
+			if value, err := valuer.Value(); err == nil {
+				v = value
+			}
+		}
+		vars = append(vars, fmt.Sprintf("%T:%v", v, v))
+	}
+	raw, err := json.Marshal([]any{
+		strings.Join(strings.Fields(db.Statement.SQL.String()), " "),
+		vars,
+		reflect.TypeOf(db.Statement.Dest).String(),
+		tables,
+		versions,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+
+	return p.prefix + "query:" + hex.EncodeToString(sum[:]), nil
+}
+
+func (p *cachePlugin) versionKey(table string) string {
+	return p.prefix + "version:" + table
+}
+
+// afterWrite 增删改成功后使表的缓存失效
+//
+//	事务中提交后再失效一次, 避免提交前的查询读到旧数据并写入缓存.
+func (p *cachePlugin) afterWrite(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || db.Statement.Table == "" {
+		return
+	}
+	tables := []string{db.Statement.Table}
+	if err := p.invalidate(db.Statement.Context, tables); err != nil {
+		zap.L().Error("查询缓存失效失败: " + err.Error())
+	}
+	if inTransaction(db) {
+		AfterCommit(db, func(ctx context.Context) error {
+			return p.invalidate(ctx, tables)
+		})
+	}
+}
+
+func (p *cachePlugin) invalidate(ctx context.Context, tables []string) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	pipe := p.rdb.Pipeline()
+	for _, table := range tables {
+		pipe.Incr(ctx, p.versionKey(table))
+	}
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+func (p *cachePlugin) stats() map[string]any {
+	return map[string]any{
+		"hits":   p.hits.Load(),
+		"misses": p.misses.Load(),
+	}
+}
+
+// cacheable 是否可以缓存, 事务中的查询与不支持序列化的结果类型不缓存
+func cacheable(db *gorm.DB) bool {
+	if inTransaction(db) || db.Statement.Dest == nil {
+		return false
+	}
+	t := reflect.TypeOf(db.Statement.Dest)
+	if t.Kind() != reflect.Ptr {
+		return false
+	}
+	t = t.Elem()
+	for t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,175 @@
+package gormx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-demo/pkg/gormx"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+type cacheUser struct {
+	ID   int64  `gorm:"primaryKey;column:id;type:bigint;not null"`
+	Name string `gorm:"column:name;type:varchar(50);not null;default:''"`
+}
+
+func (m *cacheUser) TableName() string {
+	return "t_cache_users"
+}
+
+// newCacheDB 启用查询缓存的 SQLite 内存数据库
+func newCacheDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		_ = rdb.Close()
+	})
+	db, err := gormx.NewDB(gormx.NewDBReq{
+		Driver:       gormx.DriverSQLite,
+		LogLevel:     "Error",
+		DBName:       ":memory:",
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+		CacheRedis:   rdb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = gormx.Close(db)
+	})
+	if err := gormx.AutoMigrate(db, &cacheUser{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&cacheUser{ID: 1, Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// rawRename 原生 SQL 修改, 不经过 GORM 回调, 缓存不失效
+func rawRename(t *testing.T, db *gorm.DB, name string) {
+	t.Helper()
+	if err := db.Exec("UPDATE t_cache_users SET name = ? WHERE id = 1", name).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, db *gorm.DB) string // 返回再次查询的结果
+		want string
+	}{
+		{
+			name: "命中缓存",
+			run: func(t *testing.T, db *gorm.DB) string {
+				rawRename(t, db, "b")
+				return ""
+			},
+			want: "a",
+		},
+		{
+			name: "GORM 修改后失效",
+			run: func(t *testing.T, db *gorm.DB) string {
+				if err := db.Model(&cacheUser{}).Where("id = 1").Update("name", "b").Error; err != nil {
+					t.Fatal(err)
+				}
+				return ""
+			},
+			want: "b",
+		},
+		{
+			name: "InvalidateCache",
+			run: func(t *testing.T, db *gorm.DB) string {
+				rawRename(t, db, "b")
+				if err := gormx.InvalidateCache(context.Background(), db, "t_cache_users"); err != nil {
+					t.Fatal(err)
+				}
+				return ""
+			},
+			want: "b",
+		},
+		{
+			name: "事务提交后失效",
+			run: func(t *testing.T, db *gorm.DB) string {
+				err := gormx.Transaction(context.Background(), db, func(tx *gorm.DB) error {
+					return tx.Model(&cacheUser{}).Where("id = 1").Update("name", "b").Error
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return ""
+			},
+			want: "b",
+		},
+		{
+			name: "事务中不使用缓存",
+			run: func(t *testing.T, db *gorm.DB) string {
+				rawRename(t, db, "b")
+				var name string
+				err := gormx.Transaction(context.Background(), db, func(tx *gorm.DB) error {
+					var u cacheUser
+					err := tx.Scopes(gormx.Cache(time.Minute)).Where("id = 1").Take(&u).Error
+					name = u.Name
+					return err
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return name
+			},
+			want: "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newCacheDB(t)
+			query := func() string {
+				var u cacheUser
+				if err := db.Scopes(gormx.Cache(time.Minute)).Where("id = 1").Take(&u).Error; err != nil {
+					t.Fatal(err)
+				}
+				return u.Name
+			}
+			if got := query(); got != "a" {
+				t.Fatalf("name = %s, want a", got)
+			}
+
+			got := tt.run(t, db)
+			if got == "" {
+				got = query()
+			}
+			if got != tt.want {
+				t.Errorf("name = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheNotFound(t *testing.T) {
+	db := newCacheDB(t)
+	for i := 0; i < 2; i++ { // 第二次来自缓存
+		var u cacheUser
+		err := db.Scopes(gormx.Cache(time.Minute)).Where("id = 2").Take(&u).Error
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("第 %d 次 err = %v, want %v", i+1, err, gorm.ErrRecordNotFound)
+		}
+	}
+
+	var users []cacheUser
+	for i := 0; i < 2; i++ {
+		result := db.Scopes(gormx.Cache(time.Minute)).Where("id > 1").Find(&users)
+		if result.Error != nil || result.RowsAffected != 0 || len(users) != 0 {
+			t.Fatalf("第 %d 次 err = %v, rows = %d, users = %v", i+1, result.Error, result.RowsAffected, users)
+		}
+	}
+}
@@ -2,28 +2,61 @@ package gormx
 
 import (
 	"fmt"
+	"time"
 
-	gormcache "github.com/asjdf/gorm-cache/cache"
-	gormcacheconfig "github.com/asjdf/gorm-cache/config"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// 数据库驱动
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite" // 纯 Go 实现, 不依赖 cgo, 主要用于测试
+)
+
 type NewDBReq struct {
+	Driver       string // DriverMySQL, DriverSQLite, 默认 MySQL
 	LogLevel     string
 	UserName     string
 	Password     string
 	Host         string
 	Port         int
-	DBName       string
+	DBName       string // SQLite 为数据库文件路径, :memory: 为内存数据库, 每个连接是独立的库, 需要 MaxOpenConns 为 1
 	Charset      string
 	MaxIdleConns int
 	MaxOpenConns int
+
+	Replicas             []string      // 从库 DSN, 可使用 MySQLDSN() 生成, 为空不做读写分离, 仅 MySQL 支持
+	ReplicaPolicy        string        // 从库负载均衡策略, PolicyRandom, PolicyRoundRobin, PolicyLeastConn, 默认随机
+	ReplicaCheckInterval time.Duration // 从库健康检查间隔, 默认 5 秒
+
+	CacheRedis  redis.UniversalClient // 查询缓存 redis, 为空不启用查询缓存, 见 Cache()
+	CachePrefix string                // 查询缓存 key 前缀, 默认 gormx:<DBName>:
+
+	Audit AuditConfig // 数据修改审计, Tables 为空不启用, 见 NewAuditPlugin
+}
+
+// MySQLDSN 生成 MySQL DSN
+func MySQLDSN(userName, password, host string, port int, dbName, charset string) string {
+	return fmt.Sprintf(
+		"%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
+		userName,
+		password,
+		host,
+		port,
+		dbName,
+		charset,
+	)
 }
 
 // NewDB 创建数据库链接
+//
+//	配置了从库时读写分离: 查询使用从库, 写操作与事务使用主库, 可以使用 Primary 强制使用主库.
+//	配置了 CacheRedis 时可以使用 Cache 缓存查询结果, 配置了 Audit.Tables 时记录这些表的修改. 关闭使用 Close().
 func NewDB(req NewDBReq) (*gorm.DB, error) {
 	// 日志
 	loggerConfig := logger.Config{
@@ -40,16 +73,23 @@ func NewDB(req NewDBReq) (*gorm.DB, error) {
 		loggerConfig.LogLevel = logger.Error
 	}
 	// 连接
-	dsn := fmt.Sprintf(
-		"%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
-		req.UserName,
-		req.Password,
-		req.Host,
-		req.Port,
-		req.DBName,
-		req.Charset,
-	)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+	var dialector gorm.Dialector
+	switch req.Driver {
+	case "", DriverMySQL:
+		dialector = mysql.Open(MySQLDSN(req.UserName, req.Password, req.Host, req.Port, req.DBName, req.Charset))
+	case DriverSQLite:
+		if len(req.Replicas) > 0 {
+			err := fmt.Errorf("数据库驱动 %s 不支持从库", req.Driver)
+			zap.L().Error(err.Error())
+			return nil, err
+		}
+		dialector = sqlite.Open(req.DBName)
+	default:
+		err := fmt.Errorf("不支持的数据库驱动 %s", req.Driver)
+		zap.L().Error(err.Error())
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
 		SkipDefaultTransaction: true,
 		Logger:                 NewLogger(loggerConfig),
 	})
@@ -58,15 +98,34 @@ func NewDB(req NewDBReq) (*gorm.DB, error) {
 		return nil, err
 	}
 
-	// 缓存
-	cache, err := gormcache.NewGorm2Cache(&gormcacheconfig.CacheConfig{
-		CacheLevel: gormcacheconfig.CacheLevelOff, // 缓存有 key 冲突的 BUG, 这里仅使用它的 singleflight
-	})
-	if err != nil {
+	// 查询缓存
+	if req.CacheRedis != nil {
+		prefix := req.CachePrefix
+		if prefix == "" {
+			prefix = "gormx:" + req.DBName + ":"
+		}
+		if err := db.Use(NewCachePlugin(req.CacheRedis, prefix)); err != nil {
+			zap.L().Error(err.Error())
+			return nil, err
+		}
+	}
+
+	// 通用列, 操作人与乐观锁
+	if err := db.Use(NewMixinPlugin()); err != nil {
 		zap.L().Error(err.Error())
 		return nil, err
 	}
-	if err := db.Use(cache); err != nil {
+
+	// 数据修改审计
+	if len(req.Audit.Tables) > 0 {
+		if err := db.Use(NewAuditPlugin(req.Audit)); err != nil {
+			zap.L().Error(err.Error())
+			return nil, err
+		}
+	}
+
+	// 链路追踪
+	if err := db.Use(NewTracingPlugin()); err != nil {
 		zap.L().Error(err.Error())
 		return nil, err
 	}
@@ -80,5 +139,14 @@ func NewDB(req NewDBReq) (*gorm.DB, error) {
 	sqlDB.SetMaxIdleConns(req.MaxIdleConns)
 	sqlDB.SetMaxOpenConns(req.MaxOpenConns)
 
+	// 读写分离
+	if len(req.Replicas) > 0 {
+		if err := useReplicas(db, req); err != nil {
+			zap.L().Error(err.Error())
+			_ = sqlDB.Close()
+			return nil, err
+		}
+	}
+
 	return db, nil
 }
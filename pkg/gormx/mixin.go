@@ -0,0 +1,306 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrConcurrentModification 乐观锁检查失败, 数据已被其他请求修改或删除
+var ErrConcurrentModification = errors.New("数据已被修改")
+
+// SoftDelete 软删除, 嵌入 Model 后 Delete 改为设置 deleted_at, 查询自动排除已删除的记录
+//
+//	查询已删除的记录使用 db.Unscoped(), 永久删除使用 db.Unscoped().Delete().
+//	同时嵌入 Audit, Version 时, 删除一并设置 updated_by, 版本号加一.
+type SoftDelete struct {
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;type:timestamp;index" json:"-"`
+}
+
+// Audit 操作人, 嵌入 Model 后创建与修改时自动填充 ctx 中的操作人 ID, 见 WithActor
+type Audit struct {
+	CreatedBy int64 `gorm:"column:created_by;type:bigint;not null;default:0" json:"created_by"` // 创建人
+	UpdatedBy int64 `gorm:"column:updated_by;type:bigint;not null;default:0" json:"updated_by"` // 修改人
+}
+
+// ErrVersionRequired 使用 RequireVersion 修改 Version 乐观锁的 Model 时读不到版本号
+var ErrVersionRequired = errors.New("乐观锁: 修改缺少版本号")
+
+// Version 乐观锁, 嵌入 Model 后每次修改版本号加一
+//
+//	使用结构体修改 (Save, Updates(&user), Model(&user).Updates(user)) 或 map 中包含 version 时, 只修改版本号一致的记录,
+//	否则返回 ErrConcurrentModification. 读不到版本号时 (map 不含 version, Update("col", v)) 版本号只加一不检查,
+//	需要强制检查时使用 db.Scopes(RequireVersion), 读不到版本号返回 ErrVersionRequired. 有意覆盖修改时使用 db.Scopes(SkipVersionCheck).
+type Version struct {
+	Version int64 `gorm:"column:version;type:bigint;not null;default:0" json:"version"` // 版本号
+}
+
+// 操作人类型
+const (
+	ActorUser   = "user"    // 用户
+	ActorAdmin  = "admin"   // 管理员
+	ActorAPIKey = "api_key" // API Key
+	ActorSystem = "system"  // 系统, ctx 中没有操作人时使用, 比如定时任务, 消息队列
+)
+
+// Actor 操作人
+type Actor struct {
+	Type string // 操作人类型, ActorUser, ActorAdmin, ActorAPIKey, ActorSystem
+	ID   int64  // 用户 ID, 管理员 ID 或 API Key ID
+}
+
+type actorKey struct{}
+
+// WithActor ctx 中写入操作人, 比如登录的用户
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom ctx 中的操作人, 没有时为 ActorSystem
+func ActorFrom(ctx context.Context) Actor {
+	if ctx != nil {
+		if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+			return actor
+		}
+	}
+	return Actor{Type: ActorSystem}
+}
+
+const (
+	versionCheckKey        = "gormx:version_check"
+	skipVersionCheckKey    = "gormx:skip_version_check"
+	requireVersionCheckKey = "gormx:require_version_check"
+)
+
+// SkipVersionCheck 修改时不检查 Version 乐观锁, 版本号仍然加一, 比如 db.Scopes(gormx.SkipVersionCheck).Where(...).Updates(m)
+//
+//	支持 map 与 Model 结构体, 部分列的结构体无法修改版本号.
+func SkipVersionCheck(db *gorm.DB) *gorm.DB {
+	return db.Set(skipVersionCheckKey, true)
+}
+
+// RequireVersion 修改时必须检查 Version 乐观锁, 读不到版本号 (map 不含 version, Update("col", v), 部分列的结构体) 时返回 ErrVersionRequired
+//
+//	用于避免遗漏检查, 比如 db.Scopes(gormx.RequireVersion).Where(...).Updates(m).
+func RequireVersion(db *gorm.DB) *gorm.DB {
+	return db.Set(requireVersionCheckKey, true)
+}
+
+// versionCheck 乐观锁检查, 失败时还原结构体中的版本号
+type versionCheck struct {
+	field    *schema.Field
+	dest     reflect.Value
+	expected int64
+}
+
+type mixinPlugin struct{}
+
+// NewMixinPlugin Model 通用列插件, 填充 Audit 操作人, 检查 Version 乐观锁. SoftDelete 由 GORM 实现, 删除时填充修改人与版本号
+func NewMixinPlugin() gorm.Plugin {
+	return &mixinPlugin{}
+}
+
+func (p *mixinPlugin) Name() string {
+	return "gormx:mixin"
+}
+
+func (p *mixinPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("gormx:mixin_before_create", p.beforeCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("gormx:mixin_before_update", p.beforeUpdate); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("gormx:mixin_after_update", p.afterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("gormx:mixin_before_delete", p.beforeDelete); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// beforeCreate 填充创建人与修改人, 已有值时不覆盖
+func (p *mixinPlugin) beforeCreate(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	actor := ActorFrom(db.Statement.Context).ID
+	if actor == 0 {
+		return
+	}
+
+	for _, name := range []string{"created_by", "updated_by"} {
+		field := mixinField(db.Statement.Schema, name, "Audit")
+		if field == nil {
+			continue
+		}
+		switch dest := db.Statement.Dest.(type) {
+		case map[string]any:
+			if _, ok := dest[name]; !ok {
+				dest[name] = actor
+			}
+		case []map[string]any:
+			for _, m := range dest {
+				if _, ok := m[name]; !ok {
+					m[name] = actor
+				}
+			}
+		default:
+			rv := db.Statement.ReflectValue
+			switch rv.Kind() {
+			case reflect.Slice, reflect.Array:
+				for i := 0; i < rv.Len(); i++ {
+					setIfZero(db, field, reflect.Indirect(rv.Index(i)), actor)
+				}
+			case reflect.Struct:
+				setIfZero(db, field, rv, actor)
+			}
+		}
+	}
+}
+
+// beforeUpdate 填充修改人, 版本号加一并检查
+//
+//	版本号取自 Dest: 结构体 (指针或值) 中的 version, map 中的 version 键. map 不含 version 与 Update("col", v) 读不到版本号,
+//	改为 version + 1 不检查; 部分列的结构体不修改版本号. RequireVersion 时读不到版本号返回 ErrVersionRequired.
+//	SkipVersionCheck 时 map 的版本号改为 version + 1, 结构体的版本号加一, 不添加条件.
+func (p *mixinPlugin) beforeUpdate(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	if actor := ActorFrom(db.Statement.Context).ID; actor != 0 && mixinField(db.Statement.Schema, "updated_by", "Audit") != nil {
+		db.Statement.SetColumn("updated_by", actor, true)
+	}
+
+	field := mixinField(db.Statement.Schema, "version", "Version")
+	if field == nil {
+		return
+	}
+	skip, _ := db.Get(skipVersionCheckKey)
+	require, _ := db.Get(requireVersionCheckKey)
+	check := versionCheck{field: field}
+	switch dest := db.Statement.Dest.(type) {
+	case map[string]any:
+		value, ok := dest["version"]
+		if !ok && require == true {
+			_ = db.AddError(ErrVersionRequired)
+			return
+		}
+		if !ok || skip == true {
+			dest["version"] = gorm.Expr("version + 1")
+			return
+		}
+		expected, err := cast.ToInt64E(value)
+		if err != nil {
+			_ = db.AddError(err)
+			return
+		}
+		check.expected = expected
+		dest["version"] = expected + 1
+	default:
+		rv := reflect.Indirect(reflect.ValueOf(db.Statement.Dest))
+		if rv.Kind() != reflect.Struct || rv.Type() != db.Statement.Schema.ModelType {
+			if require == true {
+				_ = db.AddError(ErrVersionRequired)
+			}
+			return
+		}
+		value, _ := field.ValueOf(db.Statement.Context, rv)
+		check.expected = value.(int64)
+		if rv.CanAddr() {
+			check.dest = rv
+			if err := field.Set(db.Statement.Context, rv, check.expected+1); err != nil {
+				_ = db.AddError(err)
+				return
+			}
+			break
+		}
+		// 结构体值, 比如 db.Model(&user).Updates(user), 由 GORM 复制后写入, 同时写入 Model
+		db.Statement.SetColumn("version", check.expected+1, true)
+		if db.Statement.ReflectValue.Kind() == reflect.Struct && db.Statement.ReflectValue.CanAddr() {
+			check.dest = db.Statement.ReflectValue
+		}
+	}
+	if skip == true {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "version"}, Value: check.expected},
+	}})
+	db.InstanceSet(versionCheckKey, check)
+}
+
+// beforeDelete 软删除时设置修改人, 版本号加一, 与 deleted_at 在同一条 UPDATE 中
+//
+//	GORM 软删除生成 SET 子句时会覆盖已有的赋值, 这里通过 SET 子句的 Builder 追加. 永久删除不处理.
+func (p *mixinPlugin) beforeDelete(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Unscoped {
+		return
+	}
+	if mixinField(db.Statement.Schema, "deleted_at", "SoftDelete") == nil {
+		return
+	}
+
+	var assignments []clause.Assignment
+	if actor := ActorFrom(db.Statement.Context).ID; actor != 0 && mixinField(db.Statement.Schema, "updated_by", "Audit") != nil {
+		assignments = append(assignments, clause.Assignment{Column: clause.Column{Name: "updated_by"}, Value: actor})
+	}
+	if mixinField(db.Statement.Schema, "version", "Version") != nil {
+		assignments = append(assignments, clause.Assignment{Column: clause.Column{Name: "version"}, Value: gorm.Expr("version + 1")})
+	}
+	if len(assignments) == 0 {
+		return
+	}
+	db.Statement.Clauses["SET"] = clause.Clause{
+		Name: "SET",
+		Builder: func(c clause.Clause, builder clause.Builder) {
+			set, _ := c.Expression.(clause.Set)
+			builder.WriteString("SET ")
+			append(set, assignments...).Build(builder)
+		},
+	}
+}
+
+// afterUpdate 版本号不一致时没有记录被修改, 返回 ErrConcurrentModification
+func (p *mixinPlugin) afterUpdate(db *gorm.DB) {
+	value, ok := db.InstanceGet(versionCheckKey)
+	if !ok {
+		return
+	}
+	check := value.(versionCheck)
+	if db.Error != nil || db.RowsAffected > 0 || db.DryRun {
+		return
+	}
+	if check.dest.IsValid() {
+		_ = check.field.Set(db.Statement.Context, check.dest, check.expected)
+	}
+	_ = db.AddError(ErrConcurrentModification)
+}
+
+// mixinField 嵌入的通用列, 同名但不是嵌入的列不处理
+func mixinField(s *schema.Schema, dbName, mixin string) *schema.Field {
+	field := s.LookUpField(dbName)
+	if field == nil || len(field.BindNames) != 2 || field.BindNames[0] != mixin {
+		return nil
+	}
+
+	return field
+}
+
+func setIfZero(db *gorm.DB, field *schema.Field, rv reflect.Value, value any) {
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+	if _, zero := field.ValueOf(db.Statement.Context, rv); zero {
+		_ = db.AddError(field.Set(db.Statement.Context, rv, value))
+	}
+}
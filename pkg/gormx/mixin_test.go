@@ -0,0 +1,351 @@
+package gormx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-demo/pkg/gormx"
+
+	"gorm.io/gorm"
+)
+
+type mixinUser struct {
+	ID   int64  `gorm:"primaryKey;column:id;type:bigint;not null"`
+	Name string `gorm:"column:name;type:varchar(50);not null;default:''"`
+	gormx.Audit
+	gormx.Version
+	gormx.SoftDelete
+}
+
+func (m *mixinUser) TableName() string {
+	return "t_mixin_users"
+}
+
+// partialUser 部分列的结构体, 读不到版本号
+type partialUser struct {
+	Name string
+}
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		update      func(db *gorm.DB, u *mixinUser) error
+		wantErr     error
+		wantVersion int64 // 数据库中的版本号, 当前为 1
+	}{
+		{
+			name: "map 版本号一致",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(&mixinUser{}).Where("id = ?", u.ID).Updates(map[string]any{"name": "b", "version": 1}).Error
+			},
+			wantVersion: 2,
+		},
+		{
+			name: "map 版本号不一致",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(&mixinUser{}).Where("id = ?", u.ID).Updates(map[string]any{"name": "b", "version": 0}).Error
+			},
+			wantErr:     gormx.ErrConcurrentModification,
+			wantVersion: 1,
+		},
+		{
+			name: "map 不含版本号",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(&mixinUser{}).Where("id = ?", u.ID).Updates(map[string]any{"name": "b"}).Error
+			},
+			wantVersion: 2,
+		},
+		{
+			name: "Update 单列",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(&mixinUser{}).Where("id = ?", u.ID).Update("name", "b").Error
+			},
+			wantVersion: 2,
+		},
+		{
+			name: "部分列的结构体",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(&mixinUser{}).Where("id = ?", u.ID).Updates(&partialUser{Name: "b"}).Error
+			},
+			wantVersion: 1,
+		},
+		{
+			name: "RequireVersion map 不含版本号",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(&mixinUser{}).Scopes(gormx.RequireVersion).Where("id = ?", u.ID).Updates(map[string]any{"name": "b"}).Error
+			},
+			wantErr:     gormx.ErrVersionRequired,
+			wantVersion: 1,
+		},
+		{
+			name: "RequireVersion Update 单列",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(&mixinUser{}).Scopes(gormx.RequireVersion).Where("id = ?", u.ID).Update("name", "b").Error
+			},
+			wantErr:     gormx.ErrVersionRequired,
+			wantVersion: 1,
+		},
+		{
+			name: "RequireVersion 部分列的结构体",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(&mixinUser{}).Scopes(gormx.RequireVersion).Where("id = ?", u.ID).Updates(&partialUser{Name: "b"}).Error
+			},
+			wantErr:     gormx.ErrVersionRequired,
+			wantVersion: 1,
+		},
+		{
+			name: "RequireVersion map 版本号一致",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(&mixinUser{}).Scopes(gormx.RequireVersion).Where("id = ?", u.ID).Updates(map[string]any{"name": "b", "version": 1}).Error
+			},
+			wantVersion: 2,
+		},
+		{
+			name: "SkipVersionCheck",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(&mixinUser{}).Scopes(gormx.SkipVersionCheck).Where("id = ?", u.ID).Updates(map[string]any{"name": "b", "version": 0}).Error
+			},
+			wantVersion: 2,
+		},
+		{
+			name: "结构体指针版本号一致",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				u.Name = "b"
+				return db.Updates(u).Error
+			},
+			wantVersion: 2,
+		},
+		{
+			name: "结构体指针版本号不一致",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				u.Name, u.Version.Version = "b", 0
+				return db.Updates(u).Error
+			},
+			wantErr:     gormx.ErrConcurrentModification,
+			wantVersion: 1,
+		},
+		{
+			name: "Save",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				u.Name = "b"
+				return db.Save(u).Error
+			},
+			wantVersion: 2,
+		},
+		{
+			name: "结构体值版本号一致",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(u).Updates(mixinUser{Name: "b", Version: gormx.Version{Version: 1}}).Error
+			},
+			wantVersion: 2,
+		},
+		{
+			name: "结构体值版本号不一致",
+			update: func(db *gorm.DB, u *mixinUser) error {
+				return db.Model(u).Updates(mixinUser{Name: "b"}).Error
+			},
+			wantErr:     gormx.ErrConcurrentModification,
+			wantVersion: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gormx.NewMemoryDB(&mixinUser{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				_ = gormx.Close(db)
+			})
+			u := &mixinUser{Name: "a"}
+			if err := db.Create(u).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Exec("UPDATE t_mixin_users SET version = 1 WHERE id = ?", u.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			u.Version.Version = 1
+
+			err = tt.update(db, u)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			var got mixinUser
+			if err := db.Take(&got, u.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got.Version.Version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", got.Version.Version, tt.wantVersion)
+			}
+			if tt.wantErr == nil && got.Name != "b" {
+				t.Errorf("name = %q, want %q", got.Name, "b")
+			}
+		})
+	}
+}
+
+func TestVersionRestoreOnConflict(t *testing.T) {
+	db, err := gormx.NewMemoryDB(&mixinUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = gormx.Close(db)
+	})
+	u := &mixinUser{Name: "a"}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("UPDATE t_mixin_users SET version = 1 WHERE id = ?", u.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	u.Name = "b"
+	if err := db.Updates(u).Error; !errors.Is(err, gormx.ErrConcurrentModification) {
+		t.Fatalf("err = %v, want %v", err, gormx.ErrConcurrentModification)
+	}
+	if u.Version.Version != 0 {
+		t.Errorf("冲突后结构体的版本号 = %d, want 0", u.Version.Version)
+	}
+}
+
+func TestAuditActor(t *testing.T) {
+	db, err := gormx.NewMemoryDB(&mixinUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = gormx.Close(db)
+	})
+
+	ctx := gormx.WithActor(context.Background(), gormx.Actor{Type: gormx.ActorUser, ID: 7})
+	u := &mixinUser{Name: "a"}
+	if err := db.WithContext(ctx).Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	if u.CreatedBy != 7 || u.UpdatedBy != 7 {
+		t.Fatalf("created_by = %d, updated_by = %d, want 7", u.CreatedBy, u.UpdatedBy)
+	}
+
+	ctx = gormx.WithActor(context.Background(), gormx.Actor{Type: gormx.ActorAdmin, ID: 9})
+	if err := db.WithContext(ctx).Model(&mixinUser{}).Where("id = ?", u.ID).
+		Updates(map[string]any{"name": "b", "version": 0}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var got mixinUser
+	if err := db.Take(&got, u.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.CreatedBy != 7 || got.UpdatedBy != 9 {
+		t.Errorf("created_by = %d, updated_by = %d, want 7, 9", got.CreatedBy, got.UpdatedBy)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	ctx := gormx.WithActor(context.Background(), gormx.Actor{Type: gormx.ActorAdmin, ID: 9})
+	tests := []struct {
+		name          string
+		delete        func(db *gorm.DB, u *mixinUser) error
+		wantDeleted   bool // 软删除, 行保留
+		wantVersion   int64
+		wantUpdatedBy int64
+	}{
+		{
+			name: "结构体",
+			delete: func(db *gorm.DB, u *mixinUser) error {
+				return db.WithContext(ctx).Delete(u).Error
+			},
+			wantDeleted:   true,
+			wantVersion:   2,
+			wantUpdatedBy: 9,
+		},
+		{
+			name: "条件",
+			delete: func(db *gorm.DB, u *mixinUser) error {
+				return db.WithContext(ctx).Where("id = ?", u.ID).Delete(&mixinUser{}).Error
+			},
+			wantDeleted:   true,
+			wantVersion:   2,
+			wantUpdatedBy: 9,
+		},
+		{
+			name: "没有操作人",
+			delete: func(db *gorm.DB, u *mixinUser) error {
+				return db.Delete(u).Error
+			},
+			wantDeleted:   true,
+			wantVersion:   2,
+			wantUpdatedBy: 7,
+		},
+		{
+			name: "永久删除",
+			delete: func(db *gorm.DB, u *mixinUser) error {
+				return db.WithContext(ctx).Unscoped().Delete(u).Error
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gormx.NewMemoryDB(&mixinUser{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				_ = gormx.Close(db)
+			})
+			u := &mixinUser{Name: "a", Audit: gormx.Audit{CreatedBy: 7, UpdatedBy: 7}, Version: gormx.Version{Version: 1}}
+			if err := db.Create(u).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.delete(db, u); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Take(&mixinUser{}, u.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Fatalf("删除后查询 err = %v, want %v", err, gorm.ErrRecordNotFound)
+			}
+			var got mixinUser
+			err = db.Unscoped().Take(&got, u.ID).Error
+			if !tt.wantDeleted {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Errorf("永久删除后 err = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.DeletedAt.Valid || got.Version.Version != tt.wantVersion || got.UpdatedBy != tt.wantUpdatedBy {
+				t.Errorf("deleted_at = %v, version = %d, updated_by = %d, want version %d, updated_by %d",
+					got.DeletedAt, got.Version.Version, got.UpdatedBy, tt.wantVersion, tt.wantUpdatedBy)
+			}
+		})
+	}
+}
+
+// 删除后旧版本号的修改失败
+func TestVersionAfterDelete(t *testing.T) {
+	db, err := gormx.NewMemoryDB(&mixinUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = gormx.Close(db)
+	})
+	u := &mixinUser{Name: "a"}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	stale := *u
+	if err := db.Delete(u).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stale.Name = "b"
+	if err := db.Unscoped().Updates(&stale).Error; !errors.Is(err, gormx.ErrConcurrentModification) {
+		t.Errorf("err = %v, want %v", err, gormx.ErrConcurrentModification)
+	}
+}
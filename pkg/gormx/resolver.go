@@ -0,0 +1,245 @@
+package gormx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-demo/pkg/gox"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// 从库负载均衡策略
+const (
+	PolicyRandom     = "random"      // 随机
+	PolicyRoundRobin = "round_robin" // 轮询
+	PolicyLeastConn  = "least_conn"  // 使用中连接数最少
+)
+
+// 已创建的读写分离, NewDB 返回的 *gorm.DB => *replicaSet
+var replicaSets sync.Map
+
+// Primary 强制使用主库
+//
+//	写后立即读等不能接受主从延迟的查询使用, 比如 db.Scopes(gormx.Primary).Find(&user). 写操作与事务默认使用主库.
+func Primary(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Write)
+}
+
+type replica struct {
+	addr string
+	db   *sql.DB
+	up   atomic.Bool
+}
+
+// replicaSet 从库集合, 实现 dbresolver.Policy, 只在健康的从库中负载均衡, 全部不可用时使用主库
+type replicaSet struct {
+	primary  *sql.DB
+	replicas []*replica
+	policy   string
+	next     atomic.Uint64
+	cancel   context.CancelFunc
+}
+
+// useReplicas 注册读写分离
+func useReplicas(db *gorm.DB, req NewDBReq) error {
+	primary, err := db.DB()
+	if err != nil {
+		return err
+	}
+	rs := &replicaSet{
+		primary: primary,
+		policy:  req.ReplicaPolicy,
+	}
+
+	dialectors := make([]gorm.Dialector, 0, len(req.Replicas)+1)
+	for _, dsn := range req.Replicas {
+		dsnConfig, err := mysqldriver.ParseDSN(dsn)
+		if err != nil {
+			return err
+		}
+		replicaDB, err := sql.Open("mysql", dsn)
+		if err != nil {
+			return err
+		}
+		replicaDB.SetMaxIdleConns(req.MaxIdleConns)
+		replicaDB.SetMaxOpenConns(req.MaxOpenConns)
+		rs.replicas = append(rs.replicas, &replica{addr: dsnConfig.Addr, db: replicaDB})
+		dialectors = append(dialectors, mysql.New(mysql.Config{
+			Conn:                      replicaDB,
+			SkipInitializeWithVersion: true, // 从库不可用不影响启动
+		}))
+	}
+	// 主库作为最后的从库, 保证只有一个从库时也会经过负载均衡策略, 从库全部不可用时使用
+	dialectors = append(dialectors, mysql.New(mysql.Config{
+		Conn:                      primary,
+		SkipInitializeWithVersion: true,
+	}))
+
+	if err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   rs,
+	})); err != nil {
+		rs.close()
+		return err
+	}
+
+	// 健康检查
+	interval := req.ReplicaCheckInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	rs.cancel = cancel
+	rs.check(ctx, interval)
+	gox.SafeGo(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rs.check(ctx, interval)
+			}
+		}
+	})
+
+	replicaSets.Store(db, rs)
+
+	return nil
+}
+
+// check 并发检查从库, 状态变化时记录日志
+func (rs *replicaSet) check(ctx context.Context, timeout time.Duration) {
+	wg := sync.WaitGroup{}
+	for _, r := range rs.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			err := r.db.PingContext(pingCtx)
+			if ctx.Err() != nil { // 已关闭
+				return
+			}
+			up := err == nil
+			if r.up.Swap(up) != up {
+				if up {
+					zap.L().Warn("从库 " + r.addr + " 已恢复")
+				} else {
+					zap.L().Error("从库 " + r.addr + " 不可用: " + err.Error())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Resolve 选择从库
+func (rs *replicaSet) Resolve([]gorm.ConnPool) gorm.ConnPool {
+	healthy := make([]*replica, 0, len(rs.replicas))
+	for _, r := range rs.replicas {
+		if r.up.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return rs.primary
+	}
+
+	switch rs.policy {
+	case PolicyRoundRobin:
+		return healthy[rs.next.Add(1)%uint64(len(healthy))].db
+	case PolicyLeastConn:
+		least := healthy[0]
+		for _, r := range healthy[1:] {
+			if r.db.Stats().InUse < least.db.Stats().InUse {
+				least = r
+			}
+		}
+		return least.db
+	default:
+		return healthy[rand.IntN(len(healthy))].db
+	}
+}
+
+func (rs *replicaSet) close() error {
+	if rs.cancel != nil {
+		rs.cancel()
+	}
+	var errs []error
+	for _, r := range rs.replicas {
+		errs = append(errs, r.db.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// SetPool 修改主库与从库的连接池大小
+func SetPool(db *gorm.DB, maxIdleConns, maxOpenConns int) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxIdleConns(maxIdleConns)
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	if value, ok := replicaSets.Load(db); ok {
+		for _, r := range value.(*replicaSet).replicas {
+			r.db.SetMaxIdleConns(maxIdleConns)
+			r.db.SetMaxOpenConns(maxOpenConns)
+		}
+	}
+
+	return nil
+}
+
+// Stats 主库与从库的连接池状态, 查询缓存命中数
+func Stats(db *gorm.DB) map[string]any {
+	stats := map[string]any{}
+	if p, ok := db.Config.Plugins[cachePluginName].(*cachePlugin); ok {
+		stats["cache"] = p.stats()
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		stats["primary"] = err.Error()
+		return stats
+	}
+	stats["primary"] = sqlDB.Stats()
+	if value, ok := replicaSets.Load(db); ok {
+		replicas := make([]map[string]any, 0)
+		for _, r := range value.(*replicaSet).replicas {
+			replicas = append(replicas, map[string]any{
+				"addr":  r.addr,
+				"up":    r.up.Load(),
+				"stats": r.db.Stats(),
+			})
+		}
+		stats["replicas"] = replicas
+	}
+
+	return stats
+}
+
+// Close 关闭主库与从库连接, 停止从库健康检查
+func Close(db *gorm.DB) error {
+	var errs []error
+	if value, ok := replicaSets.LoadAndDelete(db); ok {
+		errs = append(errs, value.(*replicaSet).close())
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	return errors.Join(append(errs, sqlDB.Close())...)
+}
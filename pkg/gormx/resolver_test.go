@@ -0,0 +1,95 @@
+package gormx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// newReplica 从库, up 为 true 时为 SQLite 内存数据库, 否则连接不可用的 MySQL 地址
+func newReplica(t *testing.T, addr string, up bool) *replica {
+	t.Helper()
+	var db *sql.DB
+	var err error
+	if up {
+		gormDB, memErr := NewMemoryDB()
+		if memErr != nil {
+			t.Fatal(memErr)
+		}
+		db, err = gormDB.DB()
+	} else {
+		db, err = sql.Open("mysql", "root:@tcp(127.0.0.1:1)/demo?timeout=200ms")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return &replica{addr: addr, db: db}
+}
+
+func TestReplicaCheck(t *testing.T) {
+	rs := &replicaSet{replicas: []*replica{newReplica(t, "a", true), newReplica(t, "b", false)}}
+	rs.check(context.Background(), time.Second)
+	if !rs.replicas[0].up.Load() || rs.replicas[1].up.Load() {
+		t.Errorf("up = %v, %v, want true, false", rs.replicas[0].up.Load(), rs.replicas[1].up.Load())
+	}
+
+	// 关闭后不再修改状态
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rs.replicas[0].up.Store(false)
+	rs.check(ctx, time.Second)
+	if rs.replicas[0].up.Load() {
+		t.Error("关闭后修改了从库状态")
+	}
+}
+
+func TestReplicaResolve(t *testing.T) {
+	primary := newReplica(t, "primary", true).db
+	a, b, c := newReplica(t, "a", true), newReplica(t, "b", true), newReplica(t, "c", true)
+
+	tests := []struct {
+		name   string
+		policy string
+		up     []bool // a, b, c
+		hold   *replica
+		want   []*replica // 依次选择的从库, nil 为主库
+	}{
+		{name: "全部不可用使用主库", policy: PolicyRandom, up: []bool{false, false, false}, want: []*replica{nil, nil}},
+		{name: "随机只选健康的从库", policy: PolicyRandom, up: []bool{false, true, false}, want: []*replica{b, b, b}},
+		{name: "默认随机", up: []bool{false, false, true}, want: []*replica{c, c}},
+		{name: "轮询跳过不可用的从库", policy: PolicyRoundRobin, up: []bool{true, false, true}, want: []*replica{c, a, c, a}},
+		{name: "最少连接", policy: PolicyLeastConn, up: []bool{true, true, false}, hold: a, want: []*replica{b, b}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &replicaSet{primary: primary, replicas: []*replica{a, b, c}, policy: tt.policy}
+			for i, up := range tt.up {
+				rs.replicas[i].up.Store(up)
+			}
+			if tt.hold != nil {
+				conn, err := tt.hold.db.Conn(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() {
+					_ = conn.Close()
+				})
+			}
+
+			for i, want := range tt.want {
+				wantDB := primary
+				if want != nil {
+					wantDB = want.db
+				}
+				if got := rs.Resolve(nil); got != wantDB {
+					t.Errorf("第 %d 次选择 %v, want %v", i+1, got, wantDB)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package gormx
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// NewMemoryDB 创建 SQLite 内存数据库并迁移 models, 用于测试
+//
+//	使用一个连接, 关闭后数据丢失. 查询缓存, 审计等插件按需通过 db.Use 开启.
+func NewMemoryDB(models ...any) (*gorm.DB, error) {
+	db, err := NewDB(NewDBReq{
+		Driver:       DriverSQLite,
+		LogLevel:     "Error",
+		DBName:       ":memory:",
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := AutoMigrate(db, models...); err != nil {
+		_ = Close(db)
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// AutoMigrate 按 Model 创建或修改表
+//
+//	线上表结构使用 SQL 迁移管理, 见 migratex, 此函数用于测试. SQLite 中只有 integer 主键自增,
+//	Model 中声明为 bigint 等类型的自增主键迁移时改为 integer.
+func AutoMigrate(db *gorm.DB, models ...any) error {
+	if db.Dialector.Name() == DriverSQLite {
+		for _, model := range models {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(model); err != nil {
+				return err
+			}
+			if field := stmt.Schema.PrioritizedPrimaryField; field != nil && field.AutoIncrement &&
+				(field.GORMDataType == schema.Int || field.GORMDataType == schema.Uint) {
+				field.DataType = "integer"
+			}
+		}
+	}
+
+	return db.AutoMigrate(models...)
+}
@@ -0,0 +1,123 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+
+	"go-demo/pkg/otelx"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracingSpanKey = "gormx:tracing_span"
+
+// tracingState 一条 SQL 的 span 以及开始前的 ctx, SQL 结束后需要还原 ctx, 避免同一 Statement 后续的 SQL 挂在已结束的 span 下
+type tracingState struct {
+	span      trace.Span
+	parentCtx context.Context
+}
+
+type tracingPlugin struct {
+	tracer trace.Tracer
+}
+
+// NewTracingPlugin GORM 链路追踪插件
+//
+//	每条 SQL 生成一个 span, 父 span 来自 db.WithContext(ctx) 传入的 ctx, 未传入 ctx 的 SQL 会成为独立的根 span.
+func NewTracingPlugin() gorm.Plugin {
+	return &tracingPlugin{
+		tracer: otelx.Tracer("go-demo/pkg/gormx"),
+	}
+}
+
+func (p *tracingPlugin) Name() string {
+	return "gormx:tracing"
+}
+
+func (p *tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("gormx:tracing_before_create", p.before("INSERT")); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("gormx:tracing_after_create", p.after); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("gormx:tracing_before_query", p.before("SELECT")); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("gormx:tracing_after_query", p.after); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("gormx:tracing_before_update", p.before("UPDATE")); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("gormx:tracing_after_update", p.after); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("gormx:tracing_before_delete", p.before("DELETE")); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("gormx:tracing_after_delete", p.after); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("gormx:tracing_before_row", p.before("ROW")); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("gormx:tracing_after_row", p.after); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("gormx:tracing_before_raw", p.before("RAW")); err != nil {
+		return err
+	}
+	if err := cb.Raw().After("gorm:raw").Register("gormx:tracing_after_raw", p.after); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *tracingPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+		parentCtx := db.Statement.Context
+		ctx, span := p.tracer.Start(parentCtx, "gorm "+operation, trace.WithSpanKind(trace.SpanKindClient))
+		span.SetAttributes(
+			semconv.DBSystemNameMySQL,
+			semconv.DBOperationName(operation),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, &tracingState{span: span, parentCtx: parentCtx})
+	}
+}
+
+func (p *tracingPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	state, ok := value.(*tracingState)
+	if !ok {
+		return
+	}
+	span := state.span
+	defer span.End()
+	db.Statement.Context = state.parentCtx
+
+	if db.Statement.Table != "" {
+		span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+	}
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -0,0 +1,138 @@
+package gormx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 死锁与锁等待超时的重试
+const (
+	txMaxRetries   = 3                     // 最大重试次数
+	txRetryBackoff = 50 * time.Millisecond // 首次重试等待时间, 之后每次翻倍, 另加随机抖动
+)
+
+type txStateKey struct{}
+
+// txState 一次事务的状态, 通过 tx.Statement.Context 传递给嵌套事务与 AfterCommit
+type txState struct {
+	mu    sync.Mutex
+	hooks []func(ctx context.Context) error
+}
+
+// Transaction 执行事务
+//
+//	fc 返回 error 或 panic 时回滚, 否则提交. fc 中使用参数 tx 操作数据库, 不要使用 db.
+//	死锁与锁等待超时会回滚后重试整个 fc, 最多重试 3 次, fc 需要可以重复执行, 不要在其中做事务外的副作用, 使用 AfterCommit.
+//	在 fc 中以 tx 调用 Transaction 为嵌套事务, 使用保存点, 嵌套事务失败只回滚到保存点, 并丢弃其中注册的 AfterCommit.
+func Transaction(ctx context.Context, db *gorm.DB, fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	// 嵌套事务
+	if state, ok := db.Statement.Context.Value(txStateKey{}).(*txState); ok && inTransaction(db) {
+		state.mu.Lock()
+		n := len(state.hooks)
+		state.mu.Unlock()
+		err := db.WithContext(context.WithValue(ctx, txStateKey{}, state)).Transaction(fc, opts...)
+		if err != nil {
+			state.mu.Lock()
+			state.hooks = state.hooks[:n]
+			state.mu.Unlock()
+		}
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		state := &txState{}
+		err := db.WithContext(context.WithValue(ctx, txStateKey{}, state)).Transaction(fc, opts...)
+		if err == nil {
+			state.runHooks(ctx)
+			return nil
+		}
+		if !retryableTxError(err) || attempt >= txMaxRetries {
+			return err
+		}
+
+		backoff := txRetryBackoff<<attempt + rand.N(txRetryBackoff)
+		zap.L().Warn(fmt.Sprintf("事务第 %d 次重试, %s 后执行: %s", attempt+1, backoff, err.Error()))
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// AfterCommit 注册事务提交后执行的函数
+//
+//	比如提交后再发送消息队列任务, 避免事务回滚后任务已发出. 按注册顺序执行, ctx 为 Transaction 的 ctx, 返回的 error 只记录日志.
+//	事务回滚或重试时丢弃已注册的函数; tx 不在 Transaction 中时立即执行.
+func AfterCommit(tx *gorm.DB, f func(ctx context.Context) error) {
+	state, ok := tx.Statement.Context.Value(txStateKey{}).(*txState)
+	if !ok || !inTransaction(tx) {
+		runHook(tx.Statement.Context, f)
+		return
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.hooks = append(state.hooks, f)
+}
+
+func (s *txState) runHooks(ctx context.Context) {
+	s.mu.Lock()
+	hooks := s.hooks
+	s.hooks = nil
+	s.mu.Unlock()
+	for _, f := range hooks {
+		runHook(ctx, f)
+	}
+}
+
+func runHook(ctx context.Context, f func(ctx context.Context) error) {
+	defer func() {
+		if r := recover(); r != nil {
+			zap.L().Error(fmt.Sprint(r))
+		}
+	}()
+	if err := f(ctx); err != nil {
+		zap.L().Error("事务提交后执行失败: " + err.Error())
+	}
+}
+
+// inTransaction 是否在事务中
+func inTransaction(db *gorm.DB) bool {
+	committer, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok && committer != nil
+}
+
+// retryableTxError 是否为可重试的错误, MySQL 死锁 1213 与锁等待超时 1205
+func retryableTxError(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+
+	return false
+}
+
+// IsDuplicateKey 是否为唯一键冲突, MySQL 1062 与 SQLite UNIQUE/PRIMARY KEY 约束
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+	var sqliteErr interface{ Code() int }
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == 2067 || sqliteErr.Code() == 1555 // SQLITE_CONSTRAINT_UNIQUE, SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+
+	return false
+}
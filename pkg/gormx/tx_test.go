@@ -0,0 +1,225 @@
+package gormx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go-demo/pkg/gormx"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+type txItem struct {
+	ID   int64  `gorm:"primaryKey;column:id;type:bigint;not null"`
+	Name string `gorm:"column:name;type:varchar(50);not null;unique;default:''"`
+}
+
+func (m *txItem) TableName() string {
+	return "t_tx_items"
+}
+
+var errTxTest = errors.New("业务错误")
+
+func TestTransaction(t *testing.T) {
+	deadlock := &mysqldriver.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+	tests := []struct {
+		name         string
+		fc           func(tx *gorm.DB, attempt int, hooks *[]string) error
+		wantErr      func(err error) bool // nil 表示没有错误
+		wantItems    []string
+		wantHooks    []string
+		wantAttempts int
+	}{
+		{
+			name: "提交",
+			fc: func(tx *gorm.DB, attempt int, hooks *[]string) error {
+				gormx.AfterCommit(tx, hookFunc(hooks, "after"))
+				return tx.Create(&txItem{Name: "a"}).Error
+			},
+			wantItems:    []string{"a"},
+			wantHooks:    []string{"after"},
+			wantAttempts: 1,
+		},
+		{
+			name: "返回错误回滚, 丢弃 AfterCommit",
+			fc: func(tx *gorm.DB, attempt int, hooks *[]string) error {
+				gormx.AfterCommit(tx, hookFunc(hooks, "after"))
+				if err := tx.Create(&txItem{Name: "a"}).Error; err != nil {
+					return err
+				}
+				return errTxTest
+			},
+			wantErr:      errorIs(errTxTest),
+			wantItems:    []string{},
+			wantHooks:    []string{},
+			wantAttempts: 1,
+		},
+		{
+			name: "嵌套事务失败只回滚到保存点",
+			fc: func(tx *gorm.DB, attempt int, hooks *[]string) error {
+				gormx.AfterCommit(tx, hookFunc(hooks, "outer"))
+				if err := tx.Create(&txItem{Name: "a"}).Error; err != nil {
+					return err
+				}
+				err := gormx.Transaction(tx.Statement.Context, tx, func(tx *gorm.DB) error {
+					gormx.AfterCommit(tx, hookFunc(hooks, "inner"))
+					if err := tx.Create(&txItem{Name: "b"}).Error; err != nil {
+						return err
+					}
+					return errTxTest
+				})
+				if !errors.Is(err, errTxTest) {
+					return fmt.Errorf("嵌套事务 err = %v", err)
+				}
+				return tx.Create(&txItem{Name: "c"}).Error
+			},
+			wantItems:    []string{"a", "c"},
+			wantHooks:    []string{"outer"},
+			wantAttempts: 1,
+		},
+		{
+			name: "死锁重试",
+			fc: func(tx *gorm.DB, attempt int, hooks *[]string) error {
+				gormx.AfterCommit(tx, hookFunc(hooks, fmt.Sprintf("after%d", attempt)))
+				if err := tx.Create(&txItem{Name: "a"}).Error; err != nil {
+					return err
+				}
+				if attempt == 1 {
+					return deadlock
+				}
+				return nil
+			},
+			wantItems:    []string{"a"},
+			wantHooks:    []string{"after2"},
+			wantAttempts: 2,
+		},
+		{
+			name: "超过重试次数",
+			fc: func(tx *gorm.DB, attempt int, hooks *[]string) error {
+				return deadlock
+			},
+			wantErr:      errorIs(deadlock),
+			wantItems:    []string{},
+			wantHooks:    []string{},
+			wantAttempts: 4,
+		},
+		{
+			name: "其他错误不重试",
+			fc: func(tx *gorm.DB, attempt int, hooks *[]string) error {
+				if err := tx.Create(&txItem{Name: "a"}).Error; err != nil {
+					return err
+				}
+				return tx.Create(&txItem{Name: "a"}).Error // 唯一键冲突
+			},
+			wantErr:      gormx.IsDuplicateKey,
+			wantItems:    []string{},
+			wantHooks:    []string{},
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gormx.NewMemoryDB(&txItem{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				_ = gormx.Close(db)
+			})
+
+			attempts := 0
+			hooks := []string{}
+			err = gormx.Transaction(context.Background(), db, func(tx *gorm.DB) error {
+				attempts++
+				return tt.fc(tx, attempts, &hooks)
+			})
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !tt.wantErr(err) {
+				t.Fatalf("err = %v", err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if !reflect.DeepEqual(hooks, tt.wantHooks) {
+				t.Errorf("hooks = %v, want %v", hooks, tt.wantHooks)
+			}
+			var items []string
+			if err := db.Model(&txItem{}).Order("name").Pluck("name", &items).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(items) == 0 {
+				items = []string{}
+			}
+			if !reflect.DeepEqual(items, tt.wantItems) {
+				t.Errorf("items = %v, want %v", items, tt.wantItems)
+			}
+		})
+	}
+}
+
+func TestAfterCommitOutsideTransaction(t *testing.T) {
+	db, err := gormx.NewMemoryDB(&txItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = gormx.Close(db)
+	})
+
+	hooks := []string{}
+	gormx.AfterCommit(db, hookFunc(&hooks, "now"))
+	if !reflect.DeepEqual(hooks, []string{"now"}) {
+		t.Errorf("hooks = %v, want [now]", hooks)
+	}
+}
+
+func TestIsDuplicateKey(t *testing.T) {
+	db, err := gormx.NewMemoryDB(&txItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = gormx.Close(db)
+	})
+	if err := db.Create(&txItem{ID: 1, Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "SQLite 唯一键", err: db.Create(&txItem{Name: "a"}).Error, want: true},
+		{name: "SQLite 主键", err: db.Create(&txItem{ID: 1, Name: "b"}).Error, want: true},
+		{name: "MySQL 1062", err: fmt.Errorf("写入失败: %w", &mysqldriver.MySQLError{Number: 1062}), want: true},
+		{name: "MySQL 死锁", err: &mysqldriver.MySQLError{Number: 1213}, want: false},
+		{name: "其他错误", err: errTxTest, want: false},
+		{name: "nil", err: nil, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gormx.IsDuplicateKey(tt.err); got != tt.want {
+				t.Errorf("IsDuplicateKey(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func errorIs(target error) func(err error) bool {
+	return func(err error) bool {
+		return errors.Is(err, target)
+	}
+}
+
+func hookFunc(hooks *[]string, name string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		*hooks = append(*hooks, name)
+		return nil
+	}
+}
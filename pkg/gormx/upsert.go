@@ -0,0 +1,234 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"gorm.io/plugin/dbresolver"
+)
+
+// 默认每条语句的行数
+const defaultUpsertBatchSize = 500
+
+// UpsertOptions 批量写入参数
+type UpsertOptions struct {
+	BatchSize       int      // 每条语句的行数, 默认 500
+	ConflictColumns []string // 唯一键列, 用于查询已存在的行与写入后的主键. MySQL 按表的全部唯一键判断冲突, SQLite 按此列判断
+	UpdateColumns   []string // 冲突时修改的列, 为空且 UpdateAll 为 false 时冲突的行不修改
+	UpdateAll       bool     // 冲突时修改除主键外的全部列
+}
+
+// UpsertFailure 写入失败的行
+type UpsertFailure struct {
+	Index int   // 在输入中的下标
+	Err   error // 数据库返回的错误
+}
+
+// UpsertResult 批量写入结果
+type UpsertResult struct {
+	IDs      []int64         // 与输入一一对应的主键, 失败的行为 0
+	Created  int             // 新增的行数
+	Updated  int             // 已存在的行数, 包括值没有变化的行
+	Existed  []int           // 已存在的行在输入中的下标, 仅指定 ConflictColumns 时统计. 冲突时不修改的, 即为没有写入的行
+	Failures []UpsertFailure // 失败的行
+}
+
+// BatchUpsert 批量写入, 冲突时修改, 即 INSERT ... ON DUPLICATE KEY UPDATE
+//
+//	rows 为 Model 结构体切片, 每 BatchSize 行一条语句, 语句失败时该批逐行写入, 失败的行记录在 Failures 中, 不影响其他行.
+//	指定 ConflictColumns 时, 每批写入前按唯一键加锁 (FOR UPDATE) 查询已存在的行, 写入后查询主键并回填到 rows; 在事务中调用时,
+//	MySQL 的间隙锁阻止其他事务在提交前写入相同的键, Existed 准确, 可能因此死锁, 由 Transaction 重试. 未指定时所有行视为新增,
+//	主键由 GORM 按自增 ID 回填, 冲突修改的行主键不准确. 返回的 error 为 ctx 取消, 查询失败, 死锁或参数错误, 此时 rows 可能已部分写入.
+//	ON DUPLICATE KEY UPDATE 不会触发 Audit 的 updated_by 填充, 需要时加入 UpdateColumns 并在 rows 中赋值.
+//	审计插件: 未指定 ConflictColumns, UpdateColumns, UpdateAll 时为普通 INSERT, 按新增记录; 否则语句带冲突处理, 不记录.
+func BatchUpsert[T any](ctx context.Context, db *gorm.DB, rows []T, opts UpsertOptions) (UpsertResult, error) {
+	result := UpsertResult{IDs: make([]int64, len(rows)), Existed: make([]int, 0), Failures: make([]UpsertFailure, 0)}
+	if len(rows) == 0 {
+		return result, nil
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultUpsertBatchSize
+	}
+	tx := db.WithContext(ctx).Clauses(dbresolver.Write).Session(&gorm.Session{})
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(&rows[0]); err != nil {
+		return result, err
+	}
+	u := upserter[T]{tx: tx, schema: stmt.Schema, pk: stmt.Schema.PrioritizedPrimaryField}
+	for _, name := range opts.ConflictColumns {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return result, fmt.Errorf("%s 没有列 %s", stmt.Schema.Table, name)
+		}
+		u.keys = append(u.keys, field)
+	}
+
+	// 冲突处理
+	if len(opts.ConflictColumns) > 0 || len(opts.UpdateColumns) > 0 || opts.UpdateAll {
+		onConflict := clause.OnConflict{}
+		for _, name := range opts.ConflictColumns {
+			onConflict.Columns = append(onConflict.Columns, clause.Column{Name: name})
+		}
+		switch {
+		case opts.UpdateAll:
+			onConflict.UpdateAll = true
+		case len(opts.UpdateColumns) > 0:
+			onConflict.DoUpdates = clause.AssignmentColumns(opts.UpdateColumns)
+		default:
+			onConflict.DoNothing = true
+		}
+		u.tx = u.tx.Clauses(onConflict).Session(&gorm.Session{})
+	}
+
+	for start := 0; start < len(rows); start += batchSize {
+		end := min(start+batchSize, len(rows))
+		if err := u.batch(ctx, rows[start:end], start, &result); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+type upserter[T any] struct {
+	tx     *gorm.DB
+	schema *schema.Schema
+	pk     *schema.Field
+	keys   []*schema.Field
+}
+
+// batch 写入一批, 失败时逐行写入
+func (u upserter[T]) batch(ctx context.Context, batch []T, offset int, result *UpsertResult) error {
+	var existing map[string]int64
+	if len(u.keys) > 0 {
+		var err error
+		if existing, err = u.ids(batch, true); err != nil {
+			return err
+		}
+	}
+
+	failed := map[int]bool{}
+	if err := u.tx.Create(&batch).Error; err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if retryableTxError(err) { // MySQL 死锁时事务已回滚, 不能继续逐行写入
+			return err
+		}
+		for i := range batch {
+			if err := u.tx.Create(&batch[i]).Error; err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				failed[i] = true
+				result.Failures = append(result.Failures, UpsertFailure{Index: offset + i, Err: err})
+			}
+		}
+	}
+
+	// 主键
+	if len(u.keys) == 0 {
+		for i := range batch {
+			if failed[i] {
+				continue
+			}
+			result.Created++
+			if u.pk != nil {
+				id, _ := u.pk.ValueOf(ctx, reflect.ValueOf(&batch[i]).Elem())
+				result.IDs[offset+i] = cast.ToInt64(id)
+			}
+		}
+		return nil
+	}
+	ids, err := u.ids(batch, false)
+	if err != nil {
+		return err
+	}
+	for i := range batch {
+		if failed[i] {
+			continue
+		}
+		key := u.key(ctx, reflect.ValueOf(&batch[i]).Elem())
+		if _, ok := existing[key]; ok {
+			result.Updated++
+			result.Existed = append(result.Existed, offset+i)
+		} else {
+			result.Created++
+		}
+		id, ok := ids[key]
+		if !ok {
+			continue
+		}
+		result.IDs[offset+i] = id
+		if u.pk != nil {
+			_ = u.pk.Set(ctx, reflect.ValueOf(&batch[i]).Elem(), id)
+		}
+	}
+
+	return nil
+}
+
+// ids 按唯一键查询主键, lock 为 true 时加锁, SQLite 中忽略
+func (u upserter[T]) ids(batch []T, lock bool) (map[string]int64, error) {
+	if u.pk == nil {
+		return nil, errors.New(u.schema.Table + " 没有主键")
+	}
+	ctx := u.tx.Statement.Context
+	values := make([][]any, 0, len(batch))
+	for i := range batch {
+		rv := reflect.ValueOf(&batch[i]).Elem()
+		value := make([]any, 0, len(u.keys))
+		for _, field := range u.keys {
+			v, _ := field.ValueOf(ctx, rv)
+			value = append(value, v)
+		}
+		values = append(values, value)
+	}
+	columns := make([]string, 0, len(u.keys))
+	for _, field := range u.keys {
+		columns = append(columns, field.DBName)
+	}
+	column, queryValues := schema.ToQueryValues(u.schema.Table, columns, values)
+
+	rows := make([]map[string]any, 0, len(batch))
+	query := u.tx.Session(&gorm.Session{NewDB: true}).Clauses(dbresolver.Write)
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	err := query.Table(u.schema.Table).
+		Select(append([]string{u.pk.DBName}, columns...)).
+		Where(clause.IN{Column: column, Values: queryValues}).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		parts := make([]string, 0, len(columns))
+		for _, name := range columns {
+			parts = append(parts, fmt.Sprint(auditValue(row[name])))
+		}
+		ids[strings.Join(parts, "\x00")] = cast.ToInt64(auditValue(row[u.pk.DBName]))
+	}
+
+	return ids, nil
+}
+
+// key 唯一键的值, 与 ids 的 key 一致
+func (u upserter[T]) key(ctx context.Context, rv reflect.Value) string {
+	parts := make([]string, 0, len(u.keys))
+	for _, field := range u.keys {
+		v, _ := field.ValueOf(ctx, rv)
+		parts = append(parts, fmt.Sprint(auditValue(v)))
+	}
+
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,224 @@
+package gormx_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"go-demo/pkg/gormx"
+)
+
+type upsertItem struct {
+	ID   int64  `gorm:"primaryKey;column:id;type:bigint;not null"`
+	Code string `gorm:"column:code;type:varchar(50);not null;unique;default:''"`
+	Name string `gorm:"column:name;type:varchar(50);not null;default:''"`
+}
+
+func (m *upsertItem) TableName() string {
+	return "t_upsert_items"
+}
+
+func TestBatchUpsert(t *testing.T) {
+	tests := []struct {
+		name         string
+		rows         []upsertItem
+		opts         gormx.UpsertOptions
+		wantIDs      []int64
+		wantCreated  int
+		wantUpdated  int
+		wantExisted  []int
+		wantFailures []int
+		wantNames    map[string]string // code -> name
+	}{
+		{
+			name:        "新增",
+			rows:        []upsertItem{{Code: "b", Name: "b1"}, {Code: "c", Name: "c1"}, {Code: "d", Name: "d1"}},
+			opts:        gormx.UpsertOptions{BatchSize: 2},
+			wantIDs:     []int64{2, 3, 4},
+			wantCreated: 3,
+			wantExisted: []int{},
+			wantNames:   map[string]string{"a": "a0", "b": "b1", "c": "c1", "d": "d1"},
+		},
+		{
+			name:         "语句失败逐行写入",
+			rows:         []upsertItem{{Code: "b", Name: "b1"}, {Code: "a", Name: "a1"}, {Code: "c", Name: "c1"}},
+			wantIDs:      []int64{2, 0, 3},
+			wantCreated:  2,
+			wantExisted:  []int{},
+			wantFailures: []int{1},
+			wantNames:    map[string]string{"a": "a0", "b": "b1", "c": "c1"},
+		},
+		{
+			name:        "冲突不修改",
+			rows:        []upsertItem{{Code: "b", Name: "b1"}, {Code: "a", Name: "a1"}},
+			opts:        gormx.UpsertOptions{ConflictColumns: []string{"code"}},
+			wantIDs:     []int64{2, 1},
+			wantCreated: 1,
+			wantUpdated: 1,
+			wantExisted: []int{1},
+			wantNames:   map[string]string{"a": "a0", "b": "b1"},
+		},
+		{
+			name:        "冲突修改指定列",
+			rows:        []upsertItem{{Code: "a", Name: "a1"}, {Code: "b", Name: "b1"}},
+			opts:        gormx.UpsertOptions{ConflictColumns: []string{"code"}, UpdateColumns: []string{"name"}},
+			wantIDs:     []int64{1, 2},
+			wantCreated: 1,
+			wantUpdated: 1,
+			wantExisted: []int{0},
+			wantNames:   map[string]string{"a": "a1", "b": "b1"},
+		},
+		{
+			name:        "冲突修改全部列, 跨批次",
+			rows:        []upsertItem{{Code: "b", Name: "b1"}, {Code: "c", Name: "c1"}, {Code: "a", Name: "a1"}},
+			opts:        gormx.UpsertOptions{BatchSize: 2, ConflictColumns: []string{"code"}, UpdateAll: true},
+			wantIDs:     []int64{2, 3, 1},
+			wantCreated: 2,
+			wantUpdated: 1,
+			wantExisted: []int{2},
+			wantNames:   map[string]string{"a": "a1", "b": "b1", "c": "c1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gormx.NewMemoryDB(&upsertItem{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				_ = gormx.Close(db)
+			})
+			if err := db.Create(&upsertItem{Code: "a", Name: "a0"}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := gormx.BatchUpsert(context.Background(), db, tt.rows, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.IDs, tt.wantIDs) {
+				t.Errorf("IDs = %v, want %v", result.IDs, tt.wantIDs)
+			}
+			for i, row := range tt.rows {
+				if row.ID != tt.wantIDs[i] {
+					t.Errorf("rows[%d].ID = %d, want %d", i, row.ID, tt.wantIDs[i])
+				}
+			}
+			if result.Created != tt.wantCreated || result.Updated != tt.wantUpdated {
+				t.Errorf("Created = %d, Updated = %d, want %d, %d", result.Created, result.Updated, tt.wantCreated, tt.wantUpdated)
+			}
+			if !reflect.DeepEqual(result.Existed, tt.wantExisted) {
+				t.Errorf("Existed = %v, want %v", result.Existed, tt.wantExisted)
+			}
+			failures := make([]int, 0, len(result.Failures))
+			for _, failure := range result.Failures {
+				if !gormx.IsDuplicateKey(failure.Err) {
+					t.Errorf("Failures[%d].Err = %v", failure.Index, failure.Err)
+				}
+				failures = append(failures, failure.Index)
+			}
+			if len(failures) > 0 || len(tt.wantFailures) > 0 {
+				if !reflect.DeepEqual(failures, tt.wantFailures) {
+					t.Errorf("Failures = %v, want %v", failures, tt.wantFailures)
+				}
+			}
+
+			var items []upsertItem
+			if err := db.Find(&items).Error; err != nil {
+				t.Fatal(err)
+			}
+			names := map[string]string{}
+			for _, item := range items {
+				names[item.Code] = item.Name
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestBatchUpsertInvalid(t *testing.T) {
+	db, err := gormx.NewMemoryDB(&upsertItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = gormx.Close(db)
+	})
+
+	result, err := gormx.BatchUpsert(context.Background(), db, []upsertItem{}, gormx.UpsertOptions{})
+	if err != nil || len(result.IDs) != 0 {
+		t.Errorf("空输入 result = %+v, err = %v", result, err)
+	}
+	_, err = gormx.BatchUpsert(context.Background(), db, []upsertItem{{Code: "a"}}, gormx.UpsertOptions{ConflictColumns: []string{"missing"}})
+	if err == nil {
+		t.Error("不存在的列应返回错误")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := gormx.BatchUpsert(ctx, db, []upsertItem{{Code: "a"}}, gormx.UpsertOptions{}); err == nil {
+		t.Error("ctx 取消应返回错误")
+	}
+}
+
+// 普通 INSERT 按新增审计, 带冲突处理的语句不审计
+func TestBatchUpsertAudit(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        gormx.UpsertOptions
+		wantRecords int
+	}{
+		{name: "普通 INSERT", opts: gormx.UpsertOptions{}, wantRecords: 2},
+		{name: "冲突不修改", opts: gormx.UpsertOptions{ConflictColumns: []string{"code"}}, wantRecords: 0},
+		{name: "冲突修改", opts: gormx.UpsertOptions{ConflictColumns: []string{"code"}, UpdateAll: true}, wantRecords: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var records []gormx.AuditRecord
+			db, err := gormx.NewDB(gormx.NewDBReq{
+				Driver:       gormx.DriverSQLite,
+				LogLevel:     "Error",
+				DBName:       ":memory:",
+				MaxIdleConns: 1,
+				MaxOpenConns: 1,
+				Audit: gormx.AuditConfig{
+					Tables: []string{"t_upsert_items"},
+					Sink: func(ctx context.Context, r []gormx.AuditRecord) error {
+						mu.Lock()
+						defer mu.Unlock()
+						records = append(records, r...)
+						return nil
+					},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				_ = gormx.Close(db)
+			})
+			if err := gormx.AutoMigrate(db, &upsertItem{}); err != nil {
+				t.Fatal(err)
+			}
+
+			rows := []upsertItem{{Code: "a"}, {Code: "b"}}
+			if _, err := gormx.BatchUpsert(context.Background(), db, rows, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(records) != tt.wantRecords {
+				t.Fatalf("审计记录数 = %d, want %d", len(records), tt.wantRecords)
+			}
+			for _, record := range records {
+				if record.Action != gormx.AuditCreate {
+					t.Errorf("action = %s, want %s", record.Action, gormx.AuditCreate)
+				}
+			}
+		})
+	}
+}
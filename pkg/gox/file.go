@@ -2,12 +2,22 @@
 package gox
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
+	"errors"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/xuri/excelize/v2"
 	"go.uber.org/zap"
 )
 
+// UTF-8 BOM, Excel 打开 CSV 时据此识别编码
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
 // PutCSV 创建 CSV 文件
 //
 //	文件不存在会创建, 文件存在会覆盖写入.
@@ -23,16 +33,54 @@ func PutCSV(name string, data [][]string) error {
 		}
 	}()
 
-	if _, err := f.WriteString("\xEF\xBB\xBF"); err != nil { // 写入 UTF-8 BOM
+	return WriteCSV(f, data)
+}
+
+// WriteCSV 写入 CSV, 带 UTF-8 BOM
+func WriteCSV(w io.Writer, data [][]string) error {
+	if _, err := w.Write(utf8BOM); err != nil { // 写入 UTF-8 BOM
 		zap.L().Error(err.Error())
 		return err
 	}
 
-	w := csv.NewWriter(f)                    // 创建一个新的写入文件流
-	if err := w.WriteAll(data); err != nil { // 写入数据
+	cw := csv.NewWriter(w)                    // 创建一个新的写入文件流
+	if err := cw.WriteAll(data); err != nil { // 写入数据
 		zap.L().Error(err.Error())
 		return err
 	}
 
 	return nil
 }
+
+// ReadTable 读取 CSV 或 XLSX 表格
+//
+//	按 name 的扩展名 .csv 或 .xlsx 识别格式, XLSX 读取第一个工作表. 每行的列数可能不同, XLSX 行尾的空单元格会被省略.
+func ReadTable(name string, r io.Reader) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		br := bufio.NewReader(r)
+		if head, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(head, utf8BOM) {
+			_, _ = br.Discard(len(utf8BOM))
+		}
+		cr := csv.NewReader(br)
+		cr.FieldsPerRecord = -1
+		return cr.ReadAll()
+	case ".xlsx":
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				zap.L().Error(err.Error())
+			}
+		}()
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("没有工作表")
+		}
+		return f.GetRows(sheets[0])
+	default:
+		return nil, errors.New("仅支持 csv, xlsx 文件")
+	}
+}
@@ -0,0 +1,174 @@
+// Package healthx 健康检查函数
+package healthx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+// Checker 依赖检查函数, 返回 nil 表示依赖可用
+type Checker func(ctx context.Context) error
+
+// Status 检查状态
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+var errPanic = errors.New("checker panic")
+
+// Result 单个依赖的检查结果
+type Result struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Report 检查报告
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Health 健康检查
+type Health struct {
+	mu       sync.RWMutex
+	checkers map[string]Checker
+	timeout  time.Duration
+}
+
+// New 创建健康检查
+//
+//	timeout 为单个依赖检查的超时时间, 超时视为不可用.
+func New(timeout time.Duration) *Health {
+	return &Health{
+		checkers: map[string]Checker{},
+		timeout:  timeout,
+	}
+}
+
+// Register 注册依赖检查
+//
+//	name 建议使用 <类型>:<名称> 的格式, 比如 mysql:demo, redis:cache. 同名注册会覆盖.
+func (h *Health) Register(name string, checker Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers[name] = checker
+}
+
+// Check 并发检查所有依赖
+func (h *Health) Check(ctx context.Context) Report {
+	h.mu.RLock()
+	checkers := make(map[string]Checker, len(h.checkers))
+	for name, checker := range h.checkers {
+		checkers[name] = checker
+	}
+	h.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checkers))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := h.checkOne(ctx, checker)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// checkOne 检查单个依赖
+//
+//	checker 不响应 ctx 时依然会按超时返回, checker 所在的 Goroutine 会在其自行结束后退出.
+func (h *Health) checkOne(ctx context.Context, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errCh <- errPanic
+			}
+		}()
+		errCh <- checker(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := Result{Status: StatusUp, Latency: time.Since(start).String()}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// LivenessHandler 存活检查 /healthz
+//
+//	进程能响应即为存活, 不检查依赖.
+func (h *Health) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusUp})
+	}
+}
+
+// ReadinessHandler 就绪检查 /readyz
+//
+//	所有依赖可用返回 200, 否则返回 503, body 为各依赖的状态与耗时.
+func (h *Health) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := h.Check(r.Context())
+		httpCode := http.StatusOK
+		if report.Status != StatusUp {
+			httpCode = http.StatusServiceUnavailable
+		}
+		writeJSON(w, httpCode, report)
+	}
+}
+
+// ServeMux 包含 /healthz 与 /readyz 的路由
+//
+//	用于没有 HTTP 服务的程序, 比如消息队列 Worker, 计划任务.
+func (h *Health) ServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", h.LivenessHandler())
+	mux.Handle("/readyz", h.ReadinessHandler())
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, httpCode int, body any) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		zap.L().Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(httpCode)
+	if _, err := w.Write(bodyBytes); err != nil {
+		zap.L().Error(err.Error())
+	}
+}
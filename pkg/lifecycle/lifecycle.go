@@ -0,0 +1,146 @@
+// Package lifecycle 生命周期管理
+//
+//	资源创建时注册关闭函数, 程序退出时按阶段依次关闭.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// Stage 关闭阶段, 按值从小到大依次执行, 同一阶段内后注册的先关闭
+type Stage int
+
+const (
+	StageServer Stage = iota // 停止接收新的请求/任务, 并等待处理中的请求/任务完成. HTTP 服务, 消息队列 Worker, 计划任务, WebSocket 连接
+	StagePool                // 等待 Goroutine 池中的任务完成
+	StageDB                  // 关闭数据库连接
+	StageRedis               // 关闭 Redis 连接
+	StageQueue               // 关闭消息队列 client
+	StageFlush               // 刷新链路追踪, 日志等缓冲数据
+)
+
+type hook struct {
+	stage Stage
+	name  string
+	close func(ctx context.Context) error
+}
+
+// Lifecycle 生命周期管理
+type Lifecycle struct {
+	mu       sync.Mutex
+	hooks    []hook
+	stopping chan struct{}
+	stopOnce sync.Once
+	once     sync.Once
+	err      error
+}
+
+// New 创建生命周期管理
+func New() *Lifecycle {
+	return &Lifecycle{
+		stopping: make(chan struct{}),
+	}
+}
+
+// Register 注册关闭函数
+//
+//	close 需要在 ctx 结束时尽快返回, name 用于日志.
+func (l *Lifecycle) Register(stage Stage, name string, close func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook{stage: stage, name: name, close: close})
+}
+
+// Stopping 开始关闭时此 channel 会被关闭
+//
+//	长时间运行的逻辑可以监听此 channel 及时退出.
+func (l *Lifecycle) Stopping() <-chan struct{} {
+	return l.stopping
+}
+
+// IsStopping 是否已开始关闭
+func (l *Lifecycle) IsStopping() bool {
+	select {
+	case <-l.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// MarkStopping 标记开始关闭, 关闭 Stopping() channel, 不执行关闭函数
+//
+//	收到退出信号时先调用, 就绪检查立即失败, 之后再调用 Shutdown. 多次调用仅执行一次.
+func (l *Lifecycle) MarkStopping() {
+	l.stopOnce.Do(func() {
+		close(l.stopping)
+	})
+}
+
+// Shutdown 按阶段关闭所有资源, 未调用 MarkStopping 时先标记开始关闭
+//
+//	ctx 为整体的超时控制, 超时后剩余的关闭函数依然会执行, 由各关闭函数自行处理 ctx 结束.
+//	多次调用仅执行一次, 返回所有关闭函数的错误.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.once.Do(func() {
+		l.MarkStopping()
+
+		l.mu.Lock()
+		hooks := make([]hook, len(l.hooks))
+		copy(hooks, l.hooks)
+		l.mu.Unlock()
+		// 阶段升序, 同阶段后注册的先关闭
+		for i, j := 0, len(hooks)-1; i < j; i, j = i+1, j-1 {
+			hooks[i], hooks[j] = hooks[j], hooks[i]
+		}
+		sort.SliceStable(hooks, func(i, j int) bool {
+			return hooks[i].stage < hooks[j].stage
+		})
+
+		errs := make([]error, 0)
+		for _, h := range hooks {
+			if err := h.close(ctx); err != nil {
+				zap.L().Error(fmt.Sprintf("%s 关闭失败: %s", h.name, err.Error()))
+				errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+				continue
+			}
+			zap.L().Info(h.name + " 已关闭")
+		}
+		l.err = errors.Join(errs...)
+	})
+
+	return l.err
+}
+
+// SignalContext 收到 SIGINT/SIGTERM 时结束的 ctx
+//
+//	服务启动失败等情况可调用 stop 主动结束.
+func SignalContext() (ctx context.Context, stop context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+// Wait 在 ctx 结束前等待 f 返回
+//
+//	用于包装不支持 ctx 的关闭函数, ctx 结束时返回 ctx 的错误, f 会在后台继续执行.
+func Wait(ctx context.Context, f func()) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,407 @@
+// Package migratex 数据库版本迁移函数
+//
+//	迁移文件命名为 <version>_<name>.up.sql 与 <version>_<name>.down.sql, version 为创建时间 20060102150405, 按 version 顺序执行.
+//	执行记录保存在 schema_migrations 表中, MySQL 执行时使用 GET_LOCK 加锁, 多个进程同时迁移时只有一个执行.
+package migratex
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	// HistoryTable 迁移记录表
+	HistoryTable = "schema_migrations"
+	// versionLayout 迁移版本号格式
+	versionLayout = "20060102150405"
+)
+
+// ErrLocked 其他进程正在执行迁移, 等待锁超时
+var ErrLocked = errors.New("其他进程正在执行迁移, 等待超时")
+
+var fileNameRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration 一个版本的迁移
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status 迁移状态
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool       // 执行失败, 需要手动修复
+	Missing   bool       // 已执行但迁移文件不存在
+	AppliedAt *time.Time // 执行时间
+}
+
+// history 迁移记录
+type history struct {
+	Version   int64     `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:name;type:varchar(255);not null;default:''"`
+	Dirty     bool      `gorm:"column:dirty;not null;default:false"`
+	AppliedAt time.Time `gorm:"column:applied_at;not null"`
+}
+
+func (history) TableName() string {
+	return HistoryTable
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db          *gorm.DB
+	migrations  []Migration
+	LockName    string        // 锁名称, 默认 migratex:<库名>
+	LockTimeout time.Duration // 等待锁的时间, 默认 60 秒
+	// Lock 自定义迁移锁, 返回解锁函数, 为空时 MySQL 使用 GET_LOCK, 其他数据库不加锁
+	Lock func(ctx context.Context) (unlock func(), err error)
+}
+
+// New 创建迁移执行器
+//
+//	source 为迁移文件所在目录, 通常为 embed.FS, 每个版本必须同时有 up 与 down 文件.
+func New(db *gorm.DB, source fs.FS) (*Migrator, error) {
+	migrations, err := Load(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:          db,
+		migrations:  migrations,
+		LockTimeout: 60 * time.Second,
+	}, nil
+}
+
+// Load 读取迁移文件, 按版本号排序
+func Load(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, err
+	}
+	migrations := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		matches := fileNameRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("迁移文件 %s 命名错误, 应为 <version>_<name>.up.sql 或 <version>_<name>.down.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("迁移文件 %s 版本号错误: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := migrations[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			migrations[version] = m
+		}
+		if m.Name != matches[2] {
+			return nil, fmt.Errorf("迁移版本 %d 存在多个名称: %s, %s", version, m.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("迁移版本 %d_%s 缺少 up 文件或内容为空", m.Version, m.Name)
+		}
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// Up 执行未执行的迁移
+//
+//	step 为执行的版本数, 0 执行全部. 返回已执行的迁移.
+func (m *Migrator) Up(ctx context.Context, step int) ([]Migration, error) {
+	done := make([]Migration, 0)
+	err := m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if step > 0 && len(done) >= step {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, migration, true); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Down 回滚最近执行的迁移
+//
+//	step 为回滚的版本数, 小于 1 时回滚 1 个. 返回已回滚的迁移.
+func (m *Migrator) Down(ctx context.Context, step int) ([]Migration, error) {
+	if step < 1 {
+		step = 1
+	}
+	done := make([]Migration, 0)
+	err := m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < step; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				return fmt.Errorf("迁移版本 %d_%s 没有 down 文件, 不能回滚", migration.Version, migration.Name)
+			}
+			if err := m.run(ctx, migration, false); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Status 全部迁移的执行状态, 按版本号排序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	histories, err := m.histories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int64]history{}
+	for _, h := range histories {
+		applied[h.Version] = h
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if h, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.Dirty = h.Dirty
+			status.AppliedAt = &h.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, h := range applied {
+		statuses = append(statuses, Status{
+			Version:   h.Version,
+			Name:      h.Name,
+			Applied:   true,
+			Dirty:     h.Dirty,
+			Missing:   true,
+			AppliedAt: &h.AppliedAt,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// Create 在 dir 中创建新版本的迁移文件, 返回 up 与 down 文件路径
+func Create(dir, name string) (string, string, error) {
+	if !regexp.MustCompile(`^\w+$`).MatchString(name) {
+		return "", "", errors.New("迁移名称只能包含字母, 数字与下划线")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+	prefix := filepath.Join(dir, time.Now().Format(versionLayout)+"_"+name)
+	up, down := prefix+".up.sql", prefix+".down.sql"
+	if err := os.WriteFile(up, []byte("-- "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte("-- "+name+" 回滚\n"), 0o644); err != nil {
+		return "", "", err
+	}
+
+	return up, down, nil
+}
+
+// run 执行一个版本的迁移
+//
+//	执行前记录为 dirty, 全部语句执行成功后清除. MySQL 的 DDL 会隐式提交, 执行失败时需要根据 dirty 记录手动修复.
+func (m *Migrator) run(ctx context.Context, migration Migration, up bool) error {
+	db := m.session(ctx)
+	content := migration.Down
+	if up {
+		content = migration.Up
+		if err := db.Create(&history{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Dirty:     true,
+			AppliedAt: time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+	} else {
+		if err := db.Model(&history{}).Where("version = ?", migration.Version).Update("dirty", true).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, statement := range Statements(content) {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("迁移版本 %d_%s 执行失败, 已标记为 dirty, 手动修复后修改或删除 %s 中的记录: %w", migration.Version, migration.Name, HistoryTable, err)
+		}
+	}
+
+	if up {
+		return db.Model(&history{}).Where("version = ?", migration.Version).Updates(map[string]any{"dirty": false, "applied_at": time.Now()}).Error
+	}
+	return db.Where("version = ?", migration.Version).Delete(&history{}).Error
+}
+
+// applied 已执行的版本, 存在 dirty 版本时返回错误
+func (m *Migrator) applied(ctx context.Context) (map[int64]history, error) {
+	histories, err := m.histories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int64]history{}
+	for _, h := range histories {
+		if h.Dirty {
+			return nil, fmt.Errorf("迁移版本 %d_%s 上次执行失败, 手动修复后修改或删除 %s 中的记录", h.Version, h.Name, HistoryTable)
+		}
+		applied[h.Version] = h
+	}
+
+	return applied, nil
+}
+
+// histories 迁移记录, 记录表不存在时创建
+func (m *Migrator) histories(ctx context.Context) ([]history, error) {
+	if err := m.session(ctx).AutoMigrate(&history{}); err != nil {
+		return nil, err
+	}
+	histories := make([]history, 0)
+	if err := m.session(ctx).Order("version").Find(&histories).Error; err != nil {
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+// session 迁移使用主库
+func (m *Migrator) session(ctx context.Context) *gorm.DB {
+	return m.db.WithContext(ctx).Clauses(dbresolver.Write).Session(&gorm.Session{})
+}
+
+// withLock 加锁执行, 见 Lock
+func (m *Migrator) withLock(ctx context.Context, f func() error) error {
+	lock := m.Lock
+	if lock == nil {
+		if m.db.Dialector.Name() != "mysql" {
+			return f()
+		}
+		lock = m.mysqlLock
+	}
+	unlock, err := lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return f()
+}
+
+// mysqlLock GET_LOCK 加锁, 等待 LockTimeout 后返回错误
+func (m *Migrator) mysqlLock(ctx context.Context) (func(), error) {
+	// GET_LOCK 为会话级别的锁, 加锁与解锁需要使用同一连接
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lockName := m.LockName
+	if lockName == "" {
+		lockName = "migratex:" + m.db.Migrator().CurrentDatabase()
+	}
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, int(m.LockTimeout.Seconds())).Scan(&locked); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if locked.Int64 != 1 {
+		_ = conn.Close()
+		return nil, ErrLocked
+	}
+
+	return func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+		_ = conn.Close()
+	}, nil
+}
+
+// Statements 将迁移文件内容拆分为单条语句
+//
+//	语句以行尾的 ; 结束, -- 开头的行为注释. 驱动不开启 multiStatements, 需要逐条执行.
+func Statements(content string) []string {
+	statements := make([]string, 0)
+	current := strings.Builder{}
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
@@ -0,0 +1,319 @@
+package migratex_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"go-demo/pkg/gormx"
+	"go-demo/pkg/migratex"
+
+	"gorm.io/gorm"
+)
+
+// 版本号按数值排序, 9 在 10 之前
+var testFS = fstest.MapFS{
+	"10_create_b.up.sql":   {Data: []byte("-- b\nCREATE TABLE b (\n  id integer\n);\nINSERT INTO b VALUES (1);\n")},
+	"10_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+	"9_create_a.up.sql":    {Data: []byte("CREATE TABLE a (id integer);")},
+	"9_create_a.down.sql":  {Data: []byte("DROP TABLE a;")},
+	"11_create_c.up.sql":   {Data: []byte("CREATE TABLE c (id integer);")},
+	"11_create_c.down.sql": {Data: []byte("DROP TABLE c;")},
+	"readme.md":            {Data: []byte("忽略非 .sql 文件")},
+}
+
+func newMigrator(t *testing.T, source fstest.MapFS) (*migratex.Migrator, *gorm.DB) {
+	t.Helper()
+	db, err := gormx.NewMemoryDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = gormx.Close(db)
+	})
+	m, err := migratex.New(db, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m, db
+}
+
+func versions(migrations []migratex.Migration) []int64 {
+	result := make([]int64, 0, len(migrations))
+	for _, m := range migrations {
+		result = append(result, m.Version)
+	}
+	return result
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  fstest.MapFS
+		want    []int64
+		wantErr bool
+	}{
+		{name: "按版本号排序", source: testFS, want: []int64{9, 10, 11}},
+		{name: "没有 down 文件", source: fstest.MapFS{"1_a.up.sql": {Data: []byte("SELECT 1;")}}, want: []int64{1}},
+		{name: "命名错误", source: fstest.MapFS{"a.up.sql": {Data: []byte("SELECT 1;")}}, wantErr: true},
+		{name: "缺少 up 文件", source: fstest.MapFS{"1_a.down.sql": {Data: []byte("SELECT 1;")}}, wantErr: true},
+		{name: "up 文件为空", source: fstest.MapFS{"1_a.up.sql": {Data: []byte("\n")}}, wantErr: true},
+		{name: "同一版本多个名称", source: fstest.MapFS{"1_a.up.sql": {Data: []byte("SELECT 1;")}, "1_b.down.sql": {Data: []byte("SELECT 1;")}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := migratex.Load(tt.source)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := versions(migrations); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("versions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	type op struct {
+		up   bool
+		step int
+		want []int64 // 本次执行或回滚的版本
+	}
+	tests := []struct {
+		name        string
+		ops         []op
+		wantApplied []int64
+		wantTables  []string
+	}{
+		{
+			name:        "全部执行",
+			ops:         []op{{up: true, want: []int64{9, 10, 11}}, {up: true, want: []int64{}}},
+			wantApplied: []int64{9, 10, 11},
+			wantTables:  []string{"a", "b", "c"},
+		},
+		{
+			name:        "按步执行",
+			ops:         []op{{up: true, step: 1, want: []int64{9}}, {up: true, step: 1, want: []int64{10}}},
+			wantApplied: []int64{9, 10},
+			wantTables:  []string{"a", "b"},
+		},
+		{
+			name:        "回滚最近 1 个",
+			ops:         []op{{up: true, want: []int64{9, 10, 11}}, {step: 0, want: []int64{11}}},
+			wantApplied: []int64{9, 10},
+			wantTables:  []string{"a", "b"},
+		},
+		{
+			name:        "回滚多个后重新执行",
+			ops:         []op{{up: true, want: []int64{9, 10, 11}}, {step: 5, want: []int64{11, 10, 9}}, {up: true, step: 2, want: []int64{9, 10}}},
+			wantApplied: []int64{9, 10},
+			wantTables:  []string{"a", "b"},
+		},
+		{
+			name:        "没有可回滚的版本",
+			ops:         []op{{step: 1, want: []int64{}}},
+			wantApplied: []int64{},
+			wantTables:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, db := newMigrator(t, testFS)
+			ctx := context.Background()
+			for i, o := range tt.ops {
+				var done []migratex.Migration
+				var err error
+				if o.up {
+					done, err = m.Up(ctx, o.step)
+				} else {
+					done, err = m.Down(ctx, o.step)
+				}
+				if err != nil {
+					t.Fatalf("第 %d 步: %v", i+1, err)
+				}
+				if got := versions(done); !reflect.DeepEqual(got, o.want) {
+					t.Fatalf("第 %d 步 versions = %v, want %v", i+1, got, o.want)
+				}
+			}
+
+			statuses, err := m.Status(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			applied := make([]int64, 0)
+			for _, s := range statuses {
+				if s.Applied {
+					applied = append(applied, s.Version)
+				}
+				if s.Dirty || s.Missing {
+					t.Errorf("status = %+v", s)
+				}
+			}
+			if !reflect.DeepEqual(applied, tt.wantApplied) {
+				t.Errorf("applied = %v, want %v", applied, tt.wantApplied)
+			}
+			tables := make([]string, 0)
+			for _, table := range []string{"a", "b", "c"} {
+				if db.Migrator().HasTable(table) {
+					tables = append(tables, table)
+				}
+			}
+			if !reflect.DeepEqual(tables, tt.wantTables) {
+				t.Errorf("tables = %v, want %v", tables, tt.wantTables)
+			}
+		})
+	}
+}
+
+func TestDirty(t *testing.T) {
+	source := fstest.MapFS{
+		"1_a.up.sql":   {Data: []byte("CREATE TABLE a (id integer);")},
+		"2_bad.up.sql": {Data: []byte("CREATE TABLE b (id integer);\nINSERT INTO missing VALUES (1);")},
+		"3_c.up.sql":   {Data: []byte("CREATE TABLE c (id integer);")},
+	}
+	m, _ := newMigrator(t, source)
+	ctx := context.Background()
+
+	done, err := m.Up(ctx, 0)
+	if err == nil {
+		t.Fatal("执行失败应返回错误")
+	}
+	if got := versions(done); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("versions = %v, want [1]", got)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[1].Applied || !statuses[1].Dirty || statuses[2].Applied {
+		t.Errorf("statuses = %+v", statuses)
+	}
+
+	// 存在 dirty 版本时不再执行
+	if _, err := m.Up(ctx, 0); err == nil {
+		t.Error("存在 dirty 版本时应返回错误")
+	}
+	if _, err := m.Down(ctx, 1); err == nil {
+		t.Error("存在 dirty 版本时应返回错误")
+	}
+}
+
+func TestStatusMissing(t *testing.T) {
+	m, db := newMigrator(t, testFS)
+	ctx := context.Background()
+	if _, err := m.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// 迁移文件被删除
+	m, err := migratex.New(db, fstest.MapFS{"9_create_a.up.sql": testFS["9_create_a.up.sql"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := make([]int64, 0)
+	for _, s := range statuses {
+		if s.Missing {
+			missing = append(missing, s.Version)
+		}
+	}
+	if !reflect.DeepEqual(missing, []int64{10, 11}) {
+		t.Errorf("missing = %v, want [10 11]", missing)
+	}
+	if _, err := m.Down(ctx, 3); err == nil {
+		t.Error("回滚缺少 down 文件的版本应返回错误")
+	}
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("加锁失败不执行", func(t *testing.T) {
+		m, db := newMigrator(t, testFS)
+		m.Lock = func(ctx context.Context) (func(), error) {
+			return nil, migratex.ErrLocked
+		}
+		if _, err := m.Up(ctx, 0); !errors.Is(err, migratex.ErrLocked) {
+			t.Fatalf("err = %v, want %v", err, migratex.ErrLocked)
+		}
+		if _, err := m.Down(ctx, 1); !errors.Is(err, migratex.ErrLocked) {
+			t.Fatalf("err = %v, want %v", err, migratex.ErrLocked)
+		}
+		if db.Migrator().HasTable("a") {
+			t.Error("加锁失败后执行了迁移")
+		}
+	})
+
+	t.Run("并发执行只执行一次", func(t *testing.T) {
+		_, db := newMigrator(t, testFS)
+		var mu sync.Mutex
+		var locks, unlocks int
+		lock := func(ctx context.Context) (func(), error) {
+			mu.Lock()
+			locks++
+			return func() {
+				unlocks++
+				mu.Unlock()
+			}, nil
+		}
+
+		var wg sync.WaitGroup
+		results := make([][]int64, 2)
+		errs := make([]error, 2)
+		for i := range results {
+			m, err := migratex.New(db, testFS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.Lock = lock
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				done, err := m.Up(ctx, 0)
+				results[i], errs[i] = versions(done), err
+			}()
+		}
+		wg.Wait()
+
+		if errs[0] != nil || errs[1] != nil {
+			t.Fatalf("errs = %v", errs)
+		}
+		if total := len(results[0]) + len(results[1]); total != 3 {
+			t.Errorf("results = %v, 共执行 %d 个, want 3", results, total)
+		}
+		if locks != 2 || unlocks != 2 {
+			t.Errorf("locks = %d, unlocks = %d, want 2, 2", locks, unlocks)
+		}
+	})
+}
+
+func TestStatements(t *testing.T) {
+	content := `-- 注释
+CREATE TABLE a (
+  id integer -- 行尾注释保留
+);
+
+INSERT INTO a VALUES (1);
+SELECT 1`
+	want := []string{
+		"CREATE TABLE a (\n  id integer -- 行尾注释保留\n);",
+		"INSERT INTO a VALUES (1);",
+		"SELECT 1",
+	}
+	if got := migratex.Statements(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("Statements = %q, want %q", got, want)
+	}
+}
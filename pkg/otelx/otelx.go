@@ -0,0 +1,105 @@
+// Package otelx OpenTelemetry 链路追踪函数
+package otelx
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Exporter 类型
+const (
+	ExporterOTLP   = "otlp"   // OTLP HTTP, 发送至 Collector/Jaeger 等
+	ExporterStdout = "stdout" // 输出到控制台, 本地调试使用
+	ExporterOff    = "off"    // 关闭
+)
+
+type NewTracerProviderReq struct {
+	ServiceName  string
+	Exporter     string  // otlp, stdout, off
+	OTLPEndpoint string  // OTLP HTTP 地址, 如 127.0.0.1:4318, 为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT 环境变量
+	OTLPInsecure bool    // OTLP 是否使用 HTTP 明文
+	SampleRatio  float64 // 采样率 [0, 1], 0 表示不采样, 有父 span 时以父 span 的采样决定为准
+}
+
+// NewTracerProvider 创建 TracerProvider
+//
+//	创建成功会设置为全局 TracerProvider 与 W3C TraceContext 传播器, gin/GORM/go-redis/asynq 的埋点都会使用全局实例.
+//	Exporter 为 off 时返回 nil, 全局 TracerProvider 保持为 noop, 埋点几乎无开销.
+func NewTracerProvider(req NewTracerProviderReq) (*sdktrace.TracerProvider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch req.Exporter {
+	case ExporterOTLP:
+		opts := make([]otlptracehttp.Option, 0)
+		if req.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(req.OTLPEndpoint))
+		}
+		if req.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterOff, "":
+		return nil, nil
+	default:
+		err = errors.New("未知 trace exporter: " + req.Exporter)
+	}
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(req.ServiceName),
+	))
+	if err != nil {
+		zap.L().Error(err.Error())
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(min(max(req.SampleRatio, 0), 1)))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp, nil
+}
+
+// Tracer 获取全局 Tracer
+//
+//	name 为埋点所在的包名, 如 go-demo/pkg/gormx.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Inject 将 ctx 中的链路信息写入 carrier
+//
+//	用于跨进程传播, 比如写入消息队列任务的 headers.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	return carrier
+}
+
+// Extract 从 carrier 中读取链路信息到 ctx
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
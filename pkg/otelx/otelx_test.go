@@ -0,0 +1,49 @@
+package otelx_test
+
+import (
+	"context"
+	"testing"
+
+	"go-demo/pkg/otelx"
+)
+
+func TestSampleRatio(t *testing.T) {
+	tests := []struct {
+		name        string
+		sampleRatio float64
+		wantSampled bool
+	}{
+		{name: "不采样", sampleRatio: 0, wantSampled: false},
+		{name: "全部采样", sampleRatio: 1, wantSampled: true},
+		{name: "小于 0 视为 0", sampleRatio: -1, wantSampled: false},
+		{name: "大于 1 视为 1", sampleRatio: 2, wantSampled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp, err := otelx.NewTracerProvider(otelx.NewTracerProviderReq{
+				ServiceName: "otelx-test",
+				Exporter:    otelx.ExporterStdout,
+				SampleRatio: tt.sampleRatio,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				_ = tp.Shutdown(context.Background())
+			})
+
+			_, span := tp.Tracer("go-demo/pkg/otelx").Start(context.Background(), "test")
+			span.End()
+			if got := span.SpanContext().IsSampled(); got != tt.wantSampled {
+				t.Errorf("sampled = %v, want %v", got, tt.wantSampled)
+			}
+		})
+	}
+}
+
+func TestExporterOff(t *testing.T) {
+	tp, err := otelx.NewTracerProvider(otelx.NewTracerProviderReq{Exporter: otelx.ExporterOff})
+	if err != nil || tp != nil {
+		t.Errorf("tp = %v, err = %v, want nil", tp, err)
+	}
+}
@@ -0,0 +1,164 @@
+// Package outbox 事务发件箱
+//
+//	任务与业务数据在同一个事务中写入 t_outbox, 事务提交后由 Relay 投递到消息队列, 回滚则不投递.
+//	投递至少一次: 投递成功但标记失败时会重复投递, 以 asynq 的 TaskID 去重, 任务处理函数仍需要幂等.
+package outbox
+
+import (
+	"fmt"
+	"time"
+
+	"go-demo/pkg/otelx"
+	"go-demo/pkg/queuex"
+
+	"github.com/goccy/go-json"
+	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
+)
+
+// 投递状态
+const (
+	StatusPending   = 0 // 待投递
+	StatusDelivered = 1 // 已投递
+	StatusFailed    = 2 // 无法投递, 比如参数无法解析, 不再重试
+)
+
+// Message 发件箱中的任务
+type Message struct {
+	ID        int64     `gorm:"primaryKey;column:id;type:bigint;not null" json:"id"`
+	TaskName  string    `gorm:"column:task_name;type:varchar(100);not null;default:''" json:"task_name"`   // 任务名
+	Payload   string    `gorm:"column:payload;type:mediumtext;not null" json:"payload"`                    // payload, JSON
+	Headers   string    `gorm:"column:headers;type:text;not null" json:"headers"`                          // 任务 headers, 链路信息, JSON
+	Options   string    `gorm:"column:options;type:text;not null" json:"options"`                          // 发送参数, JSON
+	Status    int64     `gorm:"column:status;type:tinyint;not null;default:0" json:"status"`               // 状态,0-待投递,1-已投递,2-无法投递
+	Attempts  int64     `gorm:"column:attempts;type:int;not null;default:0" json:"attempts"`               // 投递失败次数
+	LastError string    `gorm:"column:last_error;type:varchar(255);not null;default:''" json:"last_error"` // 最近一次投递失败的原因
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName get sql table name.获取数据库表名
+func (m *Message) TableName() string {
+	return "t_outbox"
+}
+
+// options 可保存的发送参数, 延时转换为定时
+type options struct {
+	Queue     string        `json:"queue,omitempty"`
+	TaskID    string        `json:"task_id,omitempty"`
+	MaxRetry  *int          `json:"max_retry,omitempty"`
+	Timeout   time.Duration `json:"timeout,omitempty"`
+	Deadline  *time.Time    `json:"deadline,omitempty"`
+	Unique    time.Duration `json:"unique,omitempty"`
+	ProcessAt *time.Time    `json:"process_at,omitempty"`
+	Retention time.Duration `json:"retention,omitempty"`
+	Group     string        `json:"group,omitempty"`
+}
+
+// Enqueue 在事务 tx 中写入任务, 提交后由 Relay 投递
+//
+//	payload 按 JSON 编码, opts 同 queuex.Enqueue, ProcessIn 从写入时开始计算. 未指定 TaskID 时为 outbox:<id>, 用于重复投递时去重.
+//	tx 的 ctx 中的链路信息随任务传递到 Worker.
+func Enqueue(tx *gorm.DB, taskName string, payload any, opts ...queuex.Option) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	headers, err := json.Marshal(otelx.Inject(tx.Statement.Context))
+	if err != nil {
+		return err
+	}
+	optionBytes, err := json.Marshal(parseOptions(opts))
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&Message{
+		TaskName: taskName,
+		Payload:  string(payloadBytes),
+		Headers:  string(headers),
+		Options:  string(optionBytes),
+	}).Error
+}
+
+// EnqueueTask 在事务 tx 中写入 TaskDef 定义的任务, 见 Enqueue
+func EnqueueTask[P any](tx *gorm.DB, def queuex.TaskDef[P], payload P, opts ...queuex.Option) error {
+	return Enqueue(tx, def.Name, payload, def.EnqueueOptions(opts...)...)
+}
+
+func parseOptions(opts []queuex.Option) options {
+	var o options
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.QueueOpt:
+			o.Queue = opt.Value().(string)
+		case asynq.TaskIDOpt:
+			o.TaskID = opt.Value().(string)
+		case asynq.MaxRetryOpt:
+			n := opt.Value().(int)
+			o.MaxRetry = &n
+		case asynq.TimeoutOpt:
+			o.Timeout = opt.Value().(time.Duration)
+		case asynq.DeadlineOpt:
+			t := opt.Value().(time.Time)
+			o.Deadline = &t
+		case asynq.UniqueOpt:
+			o.Unique = opt.Value().(time.Duration)
+		case asynq.ProcessAtOpt:
+			t := opt.Value().(time.Time)
+			o.ProcessAt = &t
+		case asynq.ProcessInOpt:
+			t := time.Now().Add(opt.Value().(time.Duration))
+			o.ProcessAt = &t
+		case asynq.RetentionOpt:
+			o.Retention = opt.Value().(time.Duration)
+		case asynq.GroupOpt:
+			o.Group = opt.Value().(string)
+		}
+	}
+
+	return o
+}
+
+// task 还原任务与发送参数
+func (m *Message) task() (*asynq.Task, []queuex.Option, error) {
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(m.Headers), &headers); err != nil {
+		return nil, nil, fmt.Errorf("headers 解析失败: %w", err)
+	}
+	var o options
+	if err := json.Unmarshal([]byte(m.Options), &o); err != nil {
+		return nil, nil, fmt.Errorf("options 解析失败: %w", err)
+	}
+
+	opts := []queuex.Option{queuex.TaskID(fmt.Sprintf("outbox:%d", m.ID))}
+	if o.TaskID != "" {
+		opts[0] = queuex.TaskID(o.TaskID)
+	}
+	if o.Queue != "" {
+		opts = append(opts, queuex.Queue(o.Queue))
+	}
+	if o.MaxRetry != nil {
+		opts = append(opts, queuex.MaxRetry(*o.MaxRetry))
+	}
+	if o.Timeout > 0 {
+		opts = append(opts, queuex.Timeout(o.Timeout))
+	}
+	if o.Deadline != nil {
+		opts = append(opts, queuex.Deadline(*o.Deadline))
+	}
+	if o.Unique > 0 {
+		opts = append(opts, queuex.Unique(o.Unique))
+	}
+	if o.ProcessAt != nil {
+		opts = append(opts, queuex.ProcessAt(*o.ProcessAt))
+	}
+	if o.Retention > 0 {
+		opts = append(opts, queuex.Retention(o.Retention))
+	}
+	if o.Group != "" {
+		opts = append(opts, queuex.Group(o.Group))
+	}
+
+	return asynq.NewTaskWithHeaders(m.TaskName, []byte(m.Payload), headers), opts, nil
+}
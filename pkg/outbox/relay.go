@@ -0,0 +1,182 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-demo/pkg/queuex"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 默认配置
+const (
+	defaultInterval        = time.Second
+	defaultBatchSize       = 100
+	defaultRetention       = 24 * time.Hour
+	defaultCleanupInterval = time.Minute
+)
+
+// RelayConfig 投递配置
+type RelayConfig struct {
+	Interval  time.Duration // 轮询间隔, 默认 1s
+	BatchSize int           // 每个事务投递的行数, 默认 100
+	Retention time.Duration // 已投递的行保留时间, 超过后删除, 默认 24h
+}
+
+// Relay 轮询发件箱, 将待投递的任务发送到消息队列
+//
+//	可以多个进程同时运行, MySQL 下以 FOR UPDATE SKIP LOCKED 分摊待投递的行.
+type Relay struct {
+	db     *gorm.DB
+	client queuex.Client
+	cfg    RelayConfig
+}
+
+// NewRelay 创建 Relay, db 为写入发件箱的数据库
+func NewRelay(db *gorm.DB, client queuex.Client, cfg RelayConfig) *Relay {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultRetention
+	}
+
+	return &Relay{db: db, client: client, cfg: cfg}
+}
+
+// Run 按 Interval 投递, 每分钟清理一次已投递的行, ctx 取消后返回
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	var lastCleanup time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// 一次取完积压的任务
+		for ctx.Err() == nil {
+			n, err := r.Deliver(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					zap.L().Error("outbox 投递失败: " + err.Error())
+				}
+				break
+			}
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		if time.Since(lastCleanup) >= defaultCleanupInterval {
+			lastCleanup = time.Now()
+			if _, err := r.Cleanup(ctx); err != nil && ctx.Err() == nil {
+				zap.L().Error("outbox 清理失败: " + err.Error())
+			}
+		}
+	}
+}
+
+// Deliver 投递一批待投递的任务, 返回本批取出的行数
+//
+//	按 id 顺序发送, 任务已存在 (TaskID 冲突或 Unique 重复) 视为已投递. 发送失败时记录原因并停止本批, 下次轮询重试;
+//	无法还原的任务标记为无法投递, 不阻塞后续任务. 发送与标记不是原子的, 标记失败时任务会再次发送, 由 TaskID 去重.
+func (r *Relay) Deliver(ctx context.Context) (int, error) {
+	var n int
+	var sendErr error
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("status = ?", StatusPending).Order("id").Limit(r.cfg.BatchSize)
+		if tx.Dialector.Name() == "mysql" {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+		var messages []Message
+		if err := query.Find(&messages).Error; err != nil {
+			return err
+		}
+		n = len(messages)
+
+		delivered := make([]int64, 0, len(messages))
+		for _, m := range messages {
+			task, opts, err := m.task()
+			if err != nil {
+				zap.L().Error(fmt.Sprintf("outbox 任务 %d 无法投递: %s", m.ID, err.Error()))
+				if err := r.mark(tx, m.ID, StatusFailed, err); err != nil {
+					return err
+				}
+				continue
+			}
+			_, err = r.client.EnqueueContext(ctx, task, opts...)
+			if err == nil || errors.Is(err, queuex.ErrTaskIDConflict) || errors.Is(err, queuex.ErrDuplicateTask) {
+				delivered = append(delivered, m.ID)
+				continue
+			}
+			sendErr = fmt.Errorf("任务 %d 发送失败: %w", m.ID, err)
+			if err := r.mark(tx, m.ID, StatusPending, err); err != nil {
+				return err
+			}
+			break
+		}
+
+		if len(delivered) > 0 {
+			if err := tx.Model(&Message{}).Where("id IN ?", delivered).
+				Updates(map[string]any{"status": StatusDelivered, "updated_at": time.Now()}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+
+	// 已投递的标记与失败原因提交后再返回发送错误
+	return n, sendErr
+}
+
+// mark 记录失败原因
+func (r *Relay) mark(tx *gorm.DB, id int64, status int, cause error) error {
+	reason := []rune(cause.Error())
+	if len(reason) > 255 {
+		reason = reason[:255]
+	}
+
+	return tx.Model(&Message{}).Where("id = ?", id).Updates(map[string]any{
+		"status":     status,
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": string(reason),
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// Cleanup 删除投递超过 Retention 的行, 返回删除的行数. 无法投递的行保留, 需要人工处理
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	db := r.db.WithContext(ctx)
+	before := time.Now().Add(-r.cfg.Retention)
+	var total int64
+	for {
+		var ids []int64
+		if err := db.Model(&Message{}).Where("status = ? AND updated_at < ?", StatusDelivered, before).
+			Order("id").Limit(1000).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		result := db.Where("id IN ?", ids).Delete(&Message{})
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+	}
+}
@@ -0,0 +1,230 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-demo/pkg/gormx"
+	"go-demo/pkg/outbox"
+	"go-demo/pkg/queuex"
+
+	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
+)
+
+var errSend = errors.New("redis 不可用")
+
+// newOutboxDB 写入 n 个待投递任务, payload 为序号
+func newOutboxDB(t *testing.T, n int, opts ...queuex.Option) *gorm.DB {
+	t.Helper()
+	db, err := gormx.NewMemoryDB(&outbox.Message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = gormx.Close(db)
+	})
+	err = gormx.Transaction(context.Background(), db, func(tx *gorm.DB) error {
+		for i := 1; i <= n; i++ {
+			if err := outbox.Enqueue(tx, "demo:task", i, opts...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []queuex.Option
+		setup        func(t *testing.T, db *gorm.DB, client *queuex.Recorder)
+		wantN        int
+		wantErr      error
+		wantStatus   []int64 // 按 id 顺序
+		wantAttempts []int64
+		wantTaskIDs  []string
+	}{
+		{
+			name:         "按批次投递",
+			wantN:        2,
+			wantStatus:   []int64{outbox.StatusDelivered, outbox.StatusDelivered, outbox.StatusPending},
+			wantAttempts: []int64{0, 0, 0},
+			wantTaskIDs:  []string{"outbox:1", "outbox:2"},
+		},
+		{
+			name: "TaskID 冲突视为已投递",
+			setup: func(t *testing.T, db *gorm.DB, client *queuex.Recorder) {
+				// 上次发送成功但标记失败
+				if _, err := client.EnqueueContext(context.Background(), asynq.NewTask("demo:task", nil), queuex.TaskID("outbox:1")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantN:        2,
+			wantStatus:   []int64{outbox.StatusDelivered, outbox.StatusDelivered, outbox.StatusPending},
+			wantAttempts: []int64{0, 0, 0},
+			wantTaskIDs:  []string{"outbox:1", "outbox:2"},
+		},
+		{
+			name:         "指定 TaskID",
+			opts:         []queuex.Option{queuex.TaskID("demo")},
+			wantN:        2,
+			wantStatus:   []int64{outbox.StatusDelivered, outbox.StatusDelivered, outbox.StatusPending},
+			wantAttempts: []int64{0, 0, 0},
+			wantTaskIDs:  []string{"demo"},
+		},
+		{
+			name: "无法还原标记为无法投递, 不阻塞后续任务",
+			setup: func(t *testing.T, db *gorm.DB, client *queuex.Recorder) {
+				if err := db.Model(&outbox.Message{}).Where("id = 1").Update("headers", "x").Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantN:        2,
+			wantStatus:   []int64{outbox.StatusFailed, outbox.StatusDelivered, outbox.StatusPending},
+			wantAttempts: []int64{1, 0, 0},
+			wantTaskIDs:  []string{"outbox:2"},
+		},
+		{
+			name: "发送失败停止本批",
+			setup: func(t *testing.T, db *gorm.DB, client *queuex.Recorder) {
+				client.SetError(errSend)
+			},
+			wantN:        2,
+			wantErr:      errSend,
+			wantStatus:   []int64{outbox.StatusPending, outbox.StatusPending, outbox.StatusPending},
+			wantAttempts: []int64{1, 0, 0},
+			wantTaskIDs:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newOutboxDB(t, 3, tt.opts...)
+			client := queuex.NewRecorder()
+			if tt.setup != nil {
+				tt.setup(t, db, client)
+			}
+
+			n, err := outbox.NewRelay(db, client, outbox.RelayConfig{BatchSize: 2}).Deliver(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if n != tt.wantN {
+				t.Errorf("n = %d, want %d", n, tt.wantN)
+			}
+
+			var messages []outbox.Message
+			if err := db.Order("id").Find(&messages).Error; err != nil {
+				t.Fatal(err)
+			}
+			status := make([]int64, 0, len(messages))
+			attempts := make([]int64, 0, len(messages))
+			for _, m := range messages {
+				status = append(status, m.Status)
+				attempts = append(attempts, m.Attempts)
+				if m.Attempts > 0 && m.LastError == "" {
+					t.Errorf("任务 %d 缺少失败原因", m.ID)
+				}
+			}
+			if !reflect.DeepEqual(status, tt.wantStatus) {
+				t.Errorf("status = %v, want %v", status, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(attempts, tt.wantAttempts) {
+				t.Errorf("attempts = %v, want %v", attempts, tt.wantAttempts)
+			}
+
+			taskIDs := []string{}
+			for _, task := range client.Tasks() {
+				taskIDs = append(taskIDs, task.TaskID)
+			}
+			if !reflect.DeepEqual(taskIDs, tt.wantTaskIDs) {
+				t.Errorf("taskIDs = %v, want %v", taskIDs, tt.wantTaskIDs)
+			}
+		})
+	}
+}
+
+// 投递还原 payload 与发送参数
+func TestDeliverTask(t *testing.T) {
+	processAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	db := newOutboxDB(t, 1, queuex.Queue("low"), queuex.MaxRetry(3), queuex.ProcessAt(processAt))
+	client := queuex.NewRecorder()
+	if _, err := outbox.NewRelay(db, client, outbox.RelayConfig{}).Deliver(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := client.Tasks("demo:task")
+	if len(tasks) != 1 {
+		t.Fatalf("tasks = %d, want 1", len(tasks))
+	}
+	var payload int
+	if err := tasks[0].Decode(&payload); err != nil || payload != 1 {
+		t.Errorf("payload = %d, err = %v", payload, err)
+	}
+	if tasks[0].Queue != "low" || tasks[0].MaxRetry != 3 || !tasks[0].ProcessAt.Equal(processAt) {
+		t.Errorf("task = %+v", tasks[0])
+	}
+}
+
+// 事务回滚不写入发件箱
+func TestEnqueueRollback(t *testing.T) {
+	db := newOutboxDB(t, 0)
+	err := gormx.Transaction(context.Background(), db, func(tx *gorm.DB) error {
+		if err := outbox.Enqueue(tx, "demo:task", 1); err != nil {
+			return err
+		}
+		return errSend
+	})
+	if !errors.Is(err, errSend) {
+		t.Fatalf("err = %v", err)
+	}
+	var count int64
+	if err := db.Model(&outbox.Message{}).Count(&count).Error; err != nil || count != 0 {
+		t.Errorf("count = %d, err = %v", count, err)
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	db := newOutboxDB(t, 4)
+	expired := time.Now().Add(-2 * time.Hour)
+	updates := []struct {
+		id     int64
+		status int64
+		at     time.Time
+	}{
+		{id: 1, status: outbox.StatusDelivered, at: expired},    // 删除
+		{id: 2, status: outbox.StatusDelivered, at: time.Now()}, // 未过期
+		{id: 3, status: outbox.StatusFailed, at: expired},       // 无法投递, 保留
+		{id: 4, status: outbox.StatusPending, at: expired},      // 待投递, 保留
+	}
+	for _, u := range updates {
+		if err := db.Model(&outbox.Message{}).Where("id = ?", u.id).
+			Updates(map[string]any{"status": u.status, "updated_at": u.at}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := outbox.NewRelay(db, queuex.NewRecorder(), outbox.RelayConfig{Retention: time.Hour}).Cleanup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1", deleted)
+	}
+	var ids []int64
+	if err := db.Model(&outbox.Message{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []int64{2, 3, 4}) {
+		t.Errorf("ids = %v, want [2 3 4]", ids)
+	}
+}
@@ -0,0 +1,74 @@
+package queuex
+
+import (
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// 队列名称, 队列及权重在配置 queues 中声明, 代码中使用的队列需要在配置中声明, 否则任务不会被处理
+const (
+	QueueDefault = "default" // 默认队列, 不指定队列时发送到此队列
+	QueueLow     = "low"     // 低优先级队列, 数据量大不紧急的任务
+)
+
+// 发送错误
+var (
+	ErrDuplicateTask  = asynq.ErrDuplicateTask  // Unique 任务在有效期内重复发送
+	ErrTaskIDConflict = asynq.ErrTaskIDConflict // TaskID 在队列中已存在
+)
+
+// Option 发送参数
+type Option = asynq.Option
+
+// Queue 发送到的队列, 默认为 QueueDefault
+func Queue(name string) Option {
+	return asynq.Queue(name)
+}
+
+// ProcessIn 延时处理
+func ProcessIn(delay time.Duration) Option {
+	return asynq.ProcessIn(delay)
+}
+
+// ProcessAt 定时处理
+func ProcessAt(t time.Time) Option {
+	return asynq.ProcessAt(t)
+}
+
+// MaxRetry 失败后的最大重试次数, 默认 25 次, 0 表示不重试. 重试次数用尽后任务归档
+func MaxRetry(n int) Option {
+	return asynq.MaxRetry(n)
+}
+
+// Timeout 单次处理的超时时间, 超时后处理函数的 ctx 被取消并按失败重试. 默认 30 分钟
+func Timeout(d time.Duration) Option {
+	return asynq.Timeout(d)
+}
+
+// Deadline 处理的截止时间, 超过后处理函数的 ctx 被取消并按失败重试. 与 Timeout 同时指定时先到者生效
+func Deadline(t time.Time) Option {
+	return asynq.Deadline(t)
+}
+
+// Unique 唯一任务, ttl 内同一队列中任务名与 payload 都相同的任务只能发送一次, 重复发送返回 ErrDuplicateTask.
+// 任务处理成功后锁释放
+func Unique(ttl time.Duration) Option {
+	return asynq.Unique(ttl)
+}
+
+// TaskID 指定任务 ID, 同一队列中 ID 已存在时返回 ErrTaskIDConflict, 用于按业务 ID 去重.
+// 默认随机生成, 任务处理完且保留期结束后 ID 可再次使用
+func TaskID(id string) Option {
+	return asynq.TaskID(id)
+}
+
+// Retention 处理成功后任务的保留时长, 期间可通过 Inspector 查看, 也占用 TaskID. 默认不保留
+func Retention(d time.Duration) Option {
+	return asynq.Retention(d)
+}
+
+// Group 任务分组, 同组任务由 Server 配置的 GroupAggregator 聚合为一个任务处理
+func Group(name string) Option {
+	return asynq.Group(name)
+}
@@ -2,104 +2,41 @@
 package queuex
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"time"
+
+	"go-demo/pkg/otelx"
 
 	"github.com/goccy/go-json"
 	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-// Enqueue 发送及时任务
-func Enqueue(client *asynq.Client, taskName string, payload map[string]any) error {
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		zap.L().Error(err.Error())
-		return err
-	}
-	task := asynq.NewTask(taskName, payloadBytes)
-	if _, err := client.Enqueue(task); err != nil {
-		zap.L().Error(err.Error())
-		return err
-	}
-
-	return nil
-}
-
-// LowEnqueue 发送低优先级及时任务
-func LowEnqueue(client *asynq.Client, taskName string, payload map[string]any) error {
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		zap.L().Error(err.Error())
-		return err
-	}
-	task := asynq.NewTask(taskName, payloadBytes)
-	if _, err := client.Enqueue(task, asynq.Queue("low")); err != nil {
-		zap.L().Error(err.Error())
-		return err
-	}
-
-	return nil
-}
-
-// EnqueueIn 发送延时任务
-func EnqueueIn(client *asynq.Client, taskName string, payload map[string]any, delay time.Duration) error {
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		zap.L().Error(err.Error())
-		return err
-	}
-	task := asynq.NewTask(taskName, payloadBytes)
-	if _, err := client.Enqueue(task, asynq.ProcessIn(delay)); err != nil {
-		zap.L().Error(err.Error())
-		return err
-	}
-
-	return nil
-}
-
-// EnqueueAt 发送定时任务
-func EnqueueAt(client *asynq.Client, taskName string, payload map[string]any, timeAt time.Time) error {
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		zap.L().Error(err.Error())
-		return err
-	}
-	task := asynq.NewTask(taskName, payloadBytes)
-	if _, err := client.Enqueue(task, asynq.ProcessAt(timeAt)); err != nil {
-		zap.L().Error(err.Error())
-		return err
-	}
-
-	return nil
-}
-
-// LowEnqueueIn 发送低优先级延时任务
-func LowEnqueueIn(client *asynq.Client, taskName string, payload map[string]any, delay time.Duration) error {
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		zap.L().Error(err.Error())
-		return err
-	}
-	task := asynq.NewTask(taskName, payloadBytes)
-	if _, err := client.Enqueue(task, asynq.Queue("low"), asynq.ProcessIn(delay)); err != nil {
-		zap.L().Error(err.Error())
-		return err
-	}
-
-	return nil
+// Client 发送任务的 client, *asynq.Client 实现了此接口, 测试时可替换为 Recorder
+type Client interface {
+	EnqueueContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
 }
 
-// LowEnqueueAt 发送低优先级定时任务
-func LowEnqueueAt(client *asynq.Client, taskName string, payload map[string]any, timeAt time.Time) error {
+// Enqueue 发送任务
+//
+//	payload 按 JSON 编码, ctx 中的链路信息会写入任务 headers, Worker 端通过 TracingMiddleware 还原.
+//	opts 见 Queue, ProcessIn, ProcessAt, MaxRetry, Timeout, Deadline, Unique, TaskID, Retention, Group,
+//	默认发送到 default 队列立即处理. 重复发送唯一任务或任务 ID 重复时返回 ErrDuplicateTask 或 ErrTaskIDConflict, 不记录错误日志.
+func Enqueue(ctx context.Context, client Client, taskName string, payload any, opts ...Option) error {
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		zap.L().Error(err.Error())
 		return err
 	}
-	task := asynq.NewTask(taskName, payloadBytes)
-	if _, err := client.Enqueue(task, asynq.Queue("low"), asynq.ProcessAt(timeAt)); err != nil {
-		zap.L().Error(err.Error())
+	task := asynq.NewTaskWithHeaders(taskName, payloadBytes, otelx.Inject(ctx))
+	if _, err := client.EnqueueContext(ctx, task, opts...); err != nil {
+		if !errors.Is(err, ErrDuplicateTask) && !errors.Is(err, ErrTaskIDConflict) {
+			zap.L().Error(err.Error())
+		}
 		return err
 	}
 
@@ -118,3 +55,36 @@ func Payload(t *asynq.Task, p any) error {
 
 	return nil
 }
+
+// TracingMiddleware 链路追踪中间件
+//
+//	从任务 headers 还原发送端的链路信息, 并为每次任务处理生成一个 span, 任务处理函数中的 ctx 即带有此 span.
+func TracingMiddleware(h asynq.Handler) asynq.Handler {
+	tracer := otelx.Tracer("go-demo/pkg/queuex")
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		ctx = otelx.Extract(ctx, t.Headers())
+		ctx, span := tracer.Start(ctx, "asynq process "+t.Type(),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				semconv.MessagingSystemKey.String("asynq"),
+				semconv.MessagingOperationTypeProcess,
+				semconv.MessagingOperationName(t.Type()),
+			),
+		)
+		defer span.End()
+		if taskID, ok := asynq.GetTaskID(ctx); ok {
+			span.SetAttributes(semconv.MessagingMessageID(taskID))
+		}
+		if queue, ok := asynq.GetQueueName(ctx); ok {
+			span.SetAttributes(semconv.MessagingDestinationName(queue))
+		}
+
+		if err := h.ProcessTask(ctx, t); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,75 @@
+package queuex_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-demo/pkg/queuex"
+)
+
+func TestEnqueue(t *testing.T) {
+	processAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	deadline := processAt.Add(time.Hour)
+	client := queuex.NewRecorder()
+	err := queuex.Enqueue(context.Background(), client, "demo:task", map[string]any{"id": 1},
+		queuex.Queue(queuex.QueueLow), queuex.ProcessAt(processAt), queuex.MaxRetry(3), queuex.Timeout(time.Minute),
+		queuex.Deadline(deadline), queuex.TaskID("demo:1"), queuex.Retention(time.Hour), queuex.Group("demo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := client.Tasks("demo:task")
+	if len(tasks) != 1 {
+		t.Fatalf("tasks = %d, want 1", len(tasks))
+	}
+	task := tasks[0]
+	var payload struct{ ID int }
+	if err := task.Decode(&payload); err != nil || payload.ID != 1 {
+		t.Errorf("payload = %+v, err = %v", payload, err)
+	}
+	if task.Queue != queuex.QueueLow || !task.ProcessAt.Equal(processAt) || task.MaxRetry != 3 || task.Timeout != time.Minute ||
+		!task.Deadline.Equal(deadline) || task.TaskID != "demo:1" || task.Retention != time.Hour || task.Group != "demo" {
+		t.Errorf("task = %+v", task)
+	}
+}
+
+func TestEnqueueDefault(t *testing.T) {
+	client := queuex.NewRecorder()
+	if err := queuex.Enqueue(context.Background(), client, "demo:task", 1); err != nil {
+		t.Fatal(err)
+	}
+	task := client.Tasks()[0]
+	if task.Queue != queuex.QueueDefault || task.MaxRetry != -1 || task.ProcessAt.After(time.Now()) {
+		t.Errorf("task = %+v", task)
+	}
+}
+
+func TestEnqueueDuplicate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []queuex.Option
+		payload int
+		wantErr error
+	}{
+		{name: "TaskID 重复", opts: []queuex.Option{queuex.TaskID("demo")}, payload: 2, wantErr: queuex.ErrTaskIDConflict},
+		{name: "TaskID 在其他队列", opts: []queuex.Option{queuex.TaskID("demo"), queuex.Queue(queuex.QueueLow)}, payload: 2},
+		{name: "唯一任务重复", opts: []queuex.Option{queuex.Unique(time.Hour)}, payload: 1, wantErr: queuex.ErrDuplicateTask},
+		{name: "唯一任务 payload 不同", opts: []queuex.Option{queuex.Unique(time.Hour)}, payload: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := queuex.NewRecorder()
+			ctx := context.Background()
+			if err := queuex.Enqueue(ctx, client, "demo:task", 1, queuex.TaskID("demo"), queuex.Unique(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			// TaskID 不同才能判断唯一任务
+			opts := append([]queuex.Option{queuex.TaskID("other")}, tt.opts...)
+			if err := queuex.Enqueue(ctx, client, "demo:task", tt.payload, opts...); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package queuex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/hibiken/asynq"
+	"github.com/samber/lo"
+)
+
+// RecordedTask Recorder 记录的任务
+type RecordedTask struct {
+	Type      string
+	Payload   []byte
+	Headers   map[string]string
+	Queue     string    // 默认为 default
+	TaskID    string    // 未指定时随机生成
+	ProcessAt time.Time // 延时与定时任务的执行时间, 及时任务为发送时间
+	MaxRetry  int       // 未指定时为 -1
+	Timeout   time.Duration
+	Deadline  time.Time
+	Unique    time.Duration
+	Retention time.Duration
+	Group     string
+}
+
+// Decode 解析 payload, p 为接收结果的指针
+func (t RecordedTask) Decode(p any) error {
+	return json.Unmarshal(t.Payload, p)
+}
+
+// Recorder 记录发送的任务, 不连接 redis, 实现了 Client, 用于测试
+//
+//	指定了 TaskID 或 Unique 的任务重复发送时, 与 asynq 一样返回 asynq.ErrTaskIDConflict 或 asynq.ErrDuplicateTask, 不考虑过期时间.
+type Recorder struct {
+	mu    sync.Mutex
+	tasks []RecordedTask
+	err   error
+	seq   int
+}
+
+// NewRecorder 创建 Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) EnqueueContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	r.seq++
+	now := time.Now()
+	recorded := RecordedTask{
+		Type:      task.Type(),
+		Payload:   task.Payload(),
+		Headers:   task.Headers(),
+		Queue:     "default",
+		TaskID:    fmt.Sprintf("recorded-%d", r.seq),
+		ProcessAt: now,
+		MaxRetry:  -1,
+	}
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.QueueOpt:
+			recorded.Queue = opt.Value().(string)
+		case asynq.TaskIDOpt:
+			recorded.TaskID = opt.Value().(string)
+		case asynq.MaxRetryOpt:
+			recorded.MaxRetry = opt.Value().(int)
+		case asynq.TimeoutOpt:
+			recorded.Timeout = opt.Value().(time.Duration)
+		case asynq.DeadlineOpt:
+			recorded.Deadline = opt.Value().(time.Time)
+		case asynq.UniqueOpt:
+			recorded.Unique = opt.Value().(time.Duration)
+		case asynq.ProcessAtOpt:
+			recorded.ProcessAt = opt.Value().(time.Time)
+		case asynq.ProcessInOpt:
+			recorded.ProcessAt = now.Add(opt.Value().(time.Duration))
+		case asynq.RetentionOpt:
+			recorded.Retention = opt.Value().(time.Duration)
+		case asynq.GroupOpt:
+			recorded.Group = opt.Value().(string)
+		}
+	}
+	for _, t := range r.tasks {
+		if t.Queue != recorded.Queue {
+			continue
+		}
+		if t.TaskID == recorded.TaskID {
+			return nil, asynq.ErrTaskIDConflict
+		}
+		if recorded.Unique > 0 && t.Unique > 0 && t.Type == recorded.Type && bytes.Equal(t.Payload, recorded.Payload) {
+			return nil, asynq.ErrDuplicateTask
+		}
+	}
+	r.tasks = append(r.tasks, recorded)
+
+	state := asynq.TaskStatePending
+	if recorded.ProcessAt.After(now) {
+		state = asynq.TaskStateScheduled
+	}
+	return &asynq.TaskInfo{
+		ID:            recorded.TaskID,
+		Queue:         recorded.Queue,
+		Type:          recorded.Type,
+		Payload:       recorded.Payload,
+		Headers:       recorded.Headers,
+		State:         state,
+		MaxRetry:      recorded.MaxRetry,
+		Timeout:       recorded.Timeout,
+		Deadline:      recorded.Deadline,
+		Group:         recorded.Group,
+		NextProcessAt: recorded.ProcessAt,
+		Retention:     recorded.Retention,
+	}, nil
+}
+
+// Tasks 已发送的任务, 按发送顺序. taskTypes 为空时返回全部任务
+func (r *Recorder) Tasks(taskTypes ...string) []RecordedTask {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tasks := make([]RecordedTask, 0, len(r.tasks))
+	for _, t := range r.tasks {
+		if len(taskTypes) == 0 || lo.Contains(taskTypes, t.Type) {
+			tasks = append(tasks, t)
+		}
+	}
+
+	return tasks
+}
+
+// SetError 之后发送任务返回 err, 用于测试发送失败, nil 恢复正常
+func (r *Recorder) SetError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// Reset 清空已记录的任务
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks = nil
+}
@@ -0,0 +1,106 @@
+package queuex
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+)
+
+// 处理统计按天保留的时长
+const statsTTL = 8 * 24 * time.Hour
+
+// 统计项, Hash 的 field 为 <任务名>:<统计项>
+const (
+	statsProcessed = "processed"
+	statsFailed    = "failed"
+	statsElapsed   = "elapsed_ms"
+)
+
+// TaskStats 任务名的处理统计
+type TaskStats struct {
+	Type      string
+	Processed int64         // 处理次数, 重试的任务每次处理计一次
+	Failed    int64         // 处理失败次数
+	Elapsed   time.Duration // 处理总耗时
+}
+
+// AvgElapsed 平均耗时
+func (s TaskStats) AvgElapsed() time.Duration {
+	if s.Processed == 0 {
+		return 0
+	}
+	return s.Elapsed / time.Duration(s.Processed)
+}
+
+// StatsMiddleware 统计每个任务名的处理次数, 失败次数与耗时
+//
+//	写入 rdb 中按天的 Hash <keyPrefix><yyyymmdd>, 保留 8 天, 由 ReadStats 读取. 写入失败只记录日志, 不影响任务结果.
+func StatsMiddleware(rdb redis.Cmdable, keyPrefix string) asynq.MiddlewareFunc {
+	return func(h asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			start := time.Now()
+			err := h.ProcessTask(ctx, t)
+
+			key := keyPrefix + start.Format("20060102")
+			pipe := rdb.Pipeline()
+			pipe.HIncrBy(ctx, key, t.Type()+":"+statsProcessed, 1)
+			if err != nil {
+				pipe.HIncrBy(ctx, key, t.Type()+":"+statsFailed, 1)
+			}
+			pipe.HIncrBy(ctx, key, t.Type()+":"+statsElapsed, time.Since(start).Milliseconds())
+			pipe.Expire(ctx, key, statsTTL)
+			if _, e := pipe.Exec(context.WithoutCancel(ctx)); e != nil {
+				zap.L().Warn("任务处理统计写入失败: " + e.Error())
+			}
+
+			return err
+		})
+	}
+}
+
+// ReadStats 读取最近 days 天 (包括今天) 的处理统计, 按任务名排序
+func ReadStats(ctx context.Context, rdb redis.Cmdable, keyPrefix string, days int) ([]TaskStats, error) {
+	stats := map[string]*TaskStats{}
+	now := time.Now()
+	for i := 0; i < days; i++ {
+		fields, err := rdb.HGetAll(ctx, keyPrefix+now.AddDate(0, 0, -i).Format("20060102")).Result()
+		if err != nil {
+			return nil, err
+		}
+		for field, value := range fields {
+			pos := strings.LastIndex(field, ":")
+			if pos < 0 {
+				continue
+			}
+			taskType := field[:pos]
+			s, ok := stats[taskType]
+			if !ok {
+				s = &TaskStats{Type: taskType}
+				stats[taskType] = s
+			}
+			n := cast.ToInt64(value)
+			switch field[pos+1:] {
+			case statsProcessed:
+				s.Processed += n
+			case statsFailed:
+				s.Failed += n
+			case statsElapsed:
+				s.Elapsed += time.Duration(n) * time.Millisecond
+			}
+		}
+	}
+
+	result := make([]TaskStats, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package queuex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/hibiken/asynq"
+)
+
+// Validator payload 校验, 由 TaskDef 的处理函数在处理前调用
+type Validator interface {
+	Validate() error
+}
+
+// TaskDef 任务定义, 任务名与 payload 类型 P 一一对应
+//
+//	发送端与 Worker 使用同一个定义, payload 按 JSON 编解码, 不再需要 map[string]any 与重复声明的匿名结构体.
+//	P 或 *P 实现了 Validator 时处理前校验, 解析或校验失败的任务不重试.
+type TaskDef[P any] struct {
+	Name    string   // 任务名, 格式为 <业务>:<操作>, 比如 User:AddUser
+	Queue   string   // 发送到的队列, 为空时为 QueueDefault
+	Options []Option // 默认的发送参数, 比如 MaxRetry, Timeout, 发送时传入的同类参数优先
+}
+
+// Enqueue 发送及时任务, opts 见 queuex.Enqueue
+func (d TaskDef[P]) Enqueue(ctx context.Context, client Client, payload P, opts ...Option) error {
+	return Enqueue(ctx, client, d.Name, payload, d.EnqueueOptions(opts...)...)
+}
+
+// EnqueueIn 发送延时任务
+func (d TaskDef[P]) EnqueueIn(ctx context.Context, client Client, payload P, delay time.Duration, opts ...Option) error {
+	return Enqueue(ctx, client, d.Name, payload, d.EnqueueOptions(append([]Option{ProcessIn(delay)}, opts...)...)...)
+}
+
+// EnqueueAt 发送定时任务
+func (d TaskDef[P]) EnqueueAt(ctx context.Context, client Client, payload P, timeAt time.Time, opts ...Option) error {
+	return Enqueue(ctx, client, d.Name, payload, d.EnqueueOptions(append([]Option{ProcessAt(timeAt)}, opts...)...)...)
+}
+
+// Handle 在 mux 上注册处理函数
+func (d TaskDef[P]) Handle(mux *asynq.ServeMux, handler func(ctx context.Context, payload P) error) {
+	mux.Handle(d.Name, d.Handler(handler))
+}
+
+// Handler 解析并校验 payload 后调用 handler
+//
+//	解析或校验失败返回 SkipRetry 的包裹, 任务不再重试. handler 中需要任务 ID, 重试次数等信息时从 ctx 中获取, 见 asynq.GetTaskID.
+func (d TaskDef[P]) Handler(handler func(ctx context.Context, payload P) error) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		var payload P
+		if err := Payload(t, &payload); err != nil {
+			return err
+		}
+		if v, ok := any(&payload).(Validator); ok {
+			if err := v.Validate(); err != nil {
+				return fmt.Errorf("%s payload 不正确: %v: %w", d.Name, err, asynq.SkipRetry)
+			}
+		}
+
+		return handler(ctx, payload)
+	})
+}
+
+// Decode 解析 payload, 用于测试中检查 Recorder 记录的任务
+func (d TaskDef[P]) Decode(payload []byte) (P, error) {
+	var p P
+	err := json.Unmarshal(payload, &p)
+	return p, err
+}
+
+// EnqueueOptions 发送时使用的全部参数, 定义中的队列与参数在前, opts 在后, asynq 中同类参数以后者为准
+func (d TaskDef[P]) EnqueueOptions(opts ...Option) []Option {
+	options := make([]Option, 0, len(d.Options)+len(opts)+1)
+	if d.Queue != "" {
+		options = append(options, Queue(d.Queue))
+	}
+	options = append(options, d.Options...)
+
+	return append(options, opts...)
+}
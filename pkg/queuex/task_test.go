@@ -0,0 +1,158 @@
+package queuex_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-demo/pkg/queuex"
+
+	"github.com/hibiken/asynq"
+)
+
+type demoPayload struct {
+	UserID int64 `json:"user_id"`
+}
+
+func (p *demoPayload) Validate() error {
+	if p.UserID <= 0 {
+		return errors.New("user_id 不能为空")
+	}
+	return nil
+}
+
+var demoTask = queuex.TaskDef[demoPayload]{
+	Name:    "demo:task",
+	Queue:   queuex.QueueLow,
+	Options: []queuex.Option{queuex.MaxRetry(3), queuex.Timeout(time.Minute)},
+}
+
+func TestEnqueueOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		def  queuex.TaskDef[demoPayload]
+		opts []queuex.Option
+		want []queuex.Option
+	}{
+		{
+			name: "定义中的队列与参数在前",
+			def:  demoTask,
+			opts: []queuex.Option{queuex.MaxRetry(5)},
+			want: []queuex.Option{queuex.Queue(queuex.QueueLow), queuex.MaxRetry(3), queuex.Timeout(time.Minute), queuex.MaxRetry(5)},
+		},
+		{
+			name: "未指定队列",
+			def:  queuex.TaskDef[demoPayload]{Name: "demo:task"},
+			opts: []queuex.Option{queuex.Queue(queuex.QueueLow)},
+			want: []queuex.Option{queuex.Queue(queuex.QueueLow)},
+		},
+		{
+			name: "没有参数",
+			def:  queuex.TaskDef[demoPayload]{Name: "demo:task"},
+			want: []queuex.Option{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.def.EnqueueOptions(tt.opts...)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].String() != tt.want[i].String() {
+					t.Errorf("got[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTaskDefEnqueue(t *testing.T) {
+	ctx := context.Background()
+	client := queuex.NewRecorder()
+	processAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := demoTask.Enqueue(ctx, client, demoPayload{UserID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := demoTask.EnqueueAt(ctx, client, demoPayload{UserID: 2}, processAt, queuex.Queue(queuex.QueueDefault), queuex.MaxRetry(5)); err != nil {
+		t.Fatal(err)
+	}
+	if err := demoTask.EnqueueIn(ctx, client, demoPayload{UserID: 3}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := client.Tasks(demoTask.Name)
+	if len(tasks) != 3 {
+		t.Fatalf("tasks = %d, want 3", len(tasks))
+	}
+	// 发送时传入的参数优先
+	wants := []struct {
+		queue    string
+		maxRetry int
+		userID   int64
+	}{
+		{queue: queuex.QueueLow, maxRetry: 3, userID: 1},
+		{queue: queuex.QueueDefault, maxRetry: 5, userID: 2},
+		{queue: queuex.QueueLow, maxRetry: 3, userID: 3},
+	}
+	userIDs := make([]int64, 0, len(tasks))
+	for i, task := range tasks {
+		payload, err := demoTask.Decode(task.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		userIDs = append(userIDs, payload.UserID)
+		if task.Queue != wants[i].queue || task.MaxRetry != wants[i].maxRetry || task.Timeout != time.Minute {
+			t.Errorf("task %d = %+v", i, task)
+		}
+	}
+	if !reflect.DeepEqual(userIDs, []int64{1, 2, 3}) {
+		t.Errorf("userIDs = %v", userIDs)
+	}
+	if !tasks[0].ProcessAt.Before(processAt) || !tasks[1].ProcessAt.Equal(processAt) || !tasks[2].ProcessAt.After(time.Now().Add(59*time.Minute)) {
+		t.Errorf("processAt = %v, %v, %v", tasks[0].ProcessAt, tasks[1].ProcessAt, tasks[2].ProcessAt)
+	}
+}
+
+func TestTaskDefHandler(t *testing.T) {
+	errHandle := errors.New("处理失败")
+	tests := []struct {
+		name       string
+		payload    string
+		handlerErr error
+		wantCalled bool
+		wantErr    error
+		wantSkip   bool
+	}{
+		{name: "正常", payload: `{"user_id":1}`, wantCalled: true},
+		{name: "处理失败按原错误重试", payload: `{"user_id":1}`, handlerErr: errHandle, wantCalled: true, wantErr: errHandle},
+		{name: "解析失败不重试", payload: `{"user_id":"x"}`, wantSkip: true},
+		{name: "校验失败不重试", payload: `{"user_id":0}`, wantSkip: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			mux := asynq.NewServeMux()
+			demoTask.Handle(mux, func(ctx context.Context, payload demoPayload) error {
+				called = true
+				if payload.UserID != 1 {
+					t.Errorf("payload = %+v", payload)
+				}
+				return tt.handlerErr
+			})
+
+			err := mux.ProcessTask(context.Background(), asynq.NewTask(demoTask.Name, []byte(tt.payload)))
+			if called != tt.wantCalled {
+				t.Errorf("called = %v, want %v", called, tt.wantCalled)
+			}
+			if errors.Is(err, asynq.SkipRetry) != tt.wantSkip {
+				t.Errorf("err = %v, wantSkip %v", err, tt.wantSkip)
+			}
+			if !tt.wantSkip && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
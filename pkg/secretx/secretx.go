@@ -0,0 +1,184 @@
+// Package secretx 密钥管理函数
+//
+//	密钥不以明文出现在配置文件中, 配置值引用密钥名称, 由 Provider 在运行时提供.
+package secretx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound 密钥不存在, Chain 遇到此错误会继续查找下一个 Provider
+var ErrNotFound = errors.New("密钥不存在")
+
+// Provider 密钥提供者
+type Provider interface {
+	// Get 获取密钥明文, 不存在时返回 ErrNotFound
+	Get(name string) (string, error)
+}
+
+// Chain 依次从多个 Provider 查找密钥, 返回第一个找到的
+func Chain(providers ...Provider) Provider {
+	return chain(providers)
+}
+
+type chain []Provider
+
+func (c chain) Get(name string) (string, error) {
+	for _, p := range c {
+		value, err := p.Get(name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return value, err
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// EnvProvider 环境变量密钥, 环境变量名为 Prefix + 大写的密钥名称, 比如 SECRET_JWT_SECRET
+type EnvProvider struct {
+	Prefix string
+}
+
+func (p EnvProvider) Get(name string) (string, error) {
+	value, ok := os.LookupEnv(p.Prefix + strings.ToUpper(name))
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return value, nil
+}
+
+// FileProvider 文件密钥, 文件路径为 Dir/<密钥名称>, 比如 Docker, Kubernetes 挂载的 /run/secrets/jwt_secret
+//
+//	文件末尾的换行会被去除.
+type FileProvider struct {
+	Dir string
+}
+
+func (p FileProvider) Get(name string) (string, error) {
+	if p.Dir == "" || name != filepath.Base(name) { // 不允许访问目录外的文件
+		return "", ErrNotFound
+	}
+	content, err := os.ReadFile(filepath.Join(p.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// 密文版本前缀, 更换加密方式时递增
+const ciphertextPrefix = "v1:"
+
+// AESGCMProvider 加密存储的密钥, AES-256-GCM 加密, 密钥名称作为附加数据, 密文不能挪作他用
+type AESGCMProvider struct {
+	aead        cipher.AEAD
+	ciphertexts map[string]string
+}
+
+// NewAESGCMProvider 创建加密密钥提供者
+//
+//	masterKey 为 base64 编码的 32 字节主密钥, 由 GenerateKey() 生成; ciphertexts 为密钥名称到 Encrypt() 密文的映射.
+func NewAESGCMProvider(masterKey string, ciphertexts map[string]string) (*AESGCMProvider, error) {
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AESGCMProvider{aead: aead, ciphertexts: ciphertexts}, nil
+}
+
+func (p *AESGCMProvider) Get(name string) (string, error) {
+	ciphertext, ok := p.ciphertexts[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return decrypt(p.aead, name, ciphertext)
+}
+
+// GenerateKey 生成 base64 编码的 32 字节主密钥
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypt 加密密钥
+//
+//	返回 v1:<base64(nonce+密文)>, 解密时 name 必须一致.
+func Encrypt(masterKey, name, plaintext string) (string, error) {
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(name))
+
+	return ciphertextPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密密钥
+func Decrypt(masterKey, name, ciphertext string) (string, error) {
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return "", err
+	}
+
+	return decrypt(aead, name, ciphertext)
+}
+
+func newAEAD(masterKey string) (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("主密钥格式错误: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, errors.New("主密钥长度必须为 32 字节")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func decrypt(aead cipher.AEAD, name, ciphertext string) (string, error) {
+	encoded, ok := strings.CutPrefix(ciphertext, ciphertextPrefix)
+	if !ok {
+		return "", fmt.Errorf("密钥 %s 密文格式错误", name)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("密钥 %s 密文格式错误: %w", name, err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("密钥 %s 密文格式错误", name)
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("密钥 %s 解密失败, 请检查主密钥: %w", name, err)
+	}
+
+	return string(plaintext), nil
+}
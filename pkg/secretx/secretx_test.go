@@ -0,0 +1,96 @@
+package secretx_test
+
+import (
+	"errors"
+	"testing"
+
+	"go-demo/pkg/secretx"
+)
+
+func TestEncrypt(t *testing.T) {
+	masterKey, err := secretx.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := secretx.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := secretx.Encrypt(masterKey, "jwt_secret", "plaintext")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		masterKey  string
+		secretName string
+		ciphertext string
+		want       string
+		wantErr    bool
+	}{
+		{name: "解密", masterKey: masterKey, secretName: "jwt_secret", ciphertext: ciphertext, want: "plaintext"},
+		{name: "密钥名称不一致", masterKey: masterKey, secretName: "db_password", ciphertext: ciphertext, wantErr: true},
+		{name: "主密钥不一致", masterKey: otherKey, secretName: "jwt_secret", ciphertext: ciphertext, wantErr: true},
+		{name: "主密钥格式错误", masterKey: "short", secretName: "jwt_secret", ciphertext: ciphertext, wantErr: true},
+		{name: "缺少版本前缀", masterKey: masterKey, secretName: "jwt_secret", ciphertext: ciphertext[3:], wantErr: true},
+		{name: "密文过短", masterKey: masterKey, secretName: "jwt_secret", ciphertext: "v1:AAAA", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := secretx.Decrypt(tt.masterKey, tt.secretName, tt.ciphertext)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type mapProvider map[string]string
+
+func (p mapProvider) Get(name string) (string, error) {
+	value, ok := p[name]
+	if !ok {
+		return "", secretx.ErrNotFound
+	}
+	return value, nil
+}
+
+type errProvider struct{}
+
+func (errProvider) Get(string) (string, error) {
+	return "", errors.New("连接失败")
+}
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers []secretx.Provider
+		want      string
+		wantErr   error
+	}{
+		{name: "返回第一个找到的", providers: []secretx.Provider{mapProvider{}, mapProvider{"a": "1"}, mapProvider{"a": "2"}}, want: "1"},
+		{name: "都不存在", providers: []secretx.Provider{mapProvider{}, mapProvider{}}, wantErr: secretx.ErrNotFound},
+		{name: "其他错误不再查找", providers: []secretx.Provider{errProvider{}, mapProvider{"a": "1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := secretx.Chain(tt.providers...).Get("a")
+			if tt.want != "" {
+				if err != nil || got != tt.want {
+					t.Fatalf("got %q, %v, want %q", got, err, tt.want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("want error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}